@@ -0,0 +1,73 @@
+package gelf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Field can be used as a glog attribute to set an additional field on the
+// GELF message. Fields take precedence over data maps and attributes
+// passed to Capture.
+func Field(key string, value interface{}) interface{} {
+	return field{key: key, value: value}
+}
+
+type facility string
+
+// Facility can be used as a glog attribute to set the _facility field of
+// the GELF message.
+func Facility(name string) interface{} {
+	return facility(name)
+}
+
+type stream string
+
+// Stream can be used as a glog attribute to set the _stream field of the
+// GELF message, which can be matched by Graylog stream rules.
+func Stream(name string) interface{} {
+	return stream(name)
+}
+
+// A ContextExtractor returns fields to add to GELF messages from a
+// context.Context passed as a glog attribute, such as a request ID.
+type ContextExtractor func(ctx context.Context) map[string]interface{}
+
+// ContextValue returns a ContextExtractor which sets the field to the value
+// of the key in the context, if it is set.
+func ContextValue(key interface{}, field string) ContextExtractor {
+	return func(ctx context.Context) map[string]interface{} {
+		v := ctx.Value(key)
+		if v == nil {
+			return nil
+		}
+		return map[string]interface{}{field: v}
+	}
+}
+
+// addFields adds fields to the data, with sanitized names. Fields replace
+// any already set with the same name. If several fields have the same
+// name once sanitized, such as "user id" and "user_id", they are added in
+// the order of their original names, with a numbered suffix after the first.
+func addFields(data map[string]interface{}, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	added := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		name := SanitizeFieldName(k)
+		for i := 2; added[name]; i++ {
+			name = fmt.Sprintf("%s_%d", SanitizeFieldName(k), i)
+		}
+		added[name] = true
+		data[name] = fields[k]
+	}
+}
@@ -0,0 +1,44 @@
+package gelf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+type requestIDKey struct{}
+
+func TestAttributes(t *testing.T) {
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-123")
+	e := glog.Event{Severity: "INFO", Message: []byte("message"), Data: []interface{}{
+		map[string]interface{}{"user id": "alice", "user_id": "bob", "user/id": "carol", "status": "map"},
+		ctx,
+		gelf.Field("status", "field"),
+		gelf.Facility("billing"),
+		gelf.Stream("payments"),
+	}}
+
+	server := newUDPServer(t)
+	ch := make(chan glog.Event, 1)
+	ch <- e
+	close(ch)
+	require.NoError(t, gelf.Capture(map[string]interface{}{"service": "web", "status": "attr"}, server.uri(""), 0, ch,
+		gelf.WithContextExtractors(gelf.ContextValue(requestIDKey{}, "request_id"))))
+	data, _ := server.receive(t)
+	m := decompress(t, data)
+
+	// Colliding names are suffixed in the order of the original names
+	assert.Equal(t, "alice", m["_user_id"])
+	assert.Equal(t, "carol", m["_user_id_2"])
+	assert.Equal(t, "bob", m["_user_id_3"])
+
+	assert.Equal(t, "req-123", m["_request_id"])
+	assert.Equal(t, "field", m["_status"])
+	assert.Equal(t, "billing", m["_facility"])
+	assert.Equal(t, "payments", m["_stream"])
+	assert.Equal(t, "web", m["_service"])
+}
@@ -0,0 +1,279 @@
+package gelf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yext/glog"
+)
+
+// Stats are statistics of the messages sent by a Backend.
+type Stats struct {
+	// Sent is the number of messages sent to the server, including the
+	// reports of dropped events.
+	Sent uint64
+	// RateLimited is the number of events dropped by the rate limits.
+	RateLimited uint64
+	// Failed is the number of messages which could not be sent, including
+	// those dropped because the buffer of a TCP or TLS connection was full.
+	Failed uint64
+	// Buffered is the number of messages waiting to be sent.
+	Buffered int
+}
+
+// flusher is implemented by writers which buffer messages.
+type flusher interface {
+	Flush() error
+}
+
+// deliveryCounter is implemented by writers which buffer messages, and so
+// count the messages sent and failed themselves.
+type deliveryCounter interface {
+	delivered() (sent, failed uint64, buffered int)
+}
+
+// encodeError is returned by writers which count the messages sent and
+// failed themselves, when a message fails before it is written or buffered.
+type encodeError struct {
+	err error
+}
+
+func (e encodeError) Error() string { return e.err.Error() }
+func (e encodeError) Unwrap() error { return e.err }
+
+// Backend sends glog events to a GELF server in the background, until the
+// event channel or the backend is closed.
+type Backend struct {
+	// Accessed atomically, and first to be aligned on 32-bit platforms.
+	sent        uint64
+	rateLimited uint64
+	failed      uint64
+
+	host  string
+	attrs map[string]interface{}
+	o     Options
+	rl    *limiter
+
+	// mu serializes writes, so that events sent with Send are not
+	// interleaved with events received from the channel.
+	mu       sync.Mutex
+	w        Writer
+	closed   bool
+	closeErr error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Start connects to the gelf server, and sends events from the channel in
+// the background, with the same rate limits as Capture. Close the backend
+// to make sure queued and buffered messages are sent before the process
+// exits.
+func Start(attrs map[string]interface{}, serverUri string, maxEventsPerSec int, eventCh <-chan glog.Event, options ...Option) (*Backend, error) {
+	o := buildOptions(options)
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	rl, err := newLimiter(maxEventsPerSec, o)
+	if err != nil {
+		return nil, err
+	}
+	w, err := Dial(serverUri)
+	if err != nil {
+		return nil, err
+	}
+	b := &Backend{
+		host:  host,
+		attrs: attrs,
+		o:     o,
+		rl:    rl,
+		w:     w,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go b.run(eventCh)
+	return b, nil
+}
+
+func (b *Backend) run(eventCh <-chan glog.Event) {
+	defer close(b.done)
+	report := time.NewTicker(b.o.DropReportInterval)
+	defer report.Stop()
+
+	// Errors sending individual messages are ignored, as they can't be
+	// logged without producing more events.
+	for {
+		select {
+		case e, ok := <-eventCh:
+			if !ok {
+				b.shutdown()
+				return
+			}
+			b.capture(e)
+		case <-report.C:
+			b.reportDrops()
+		case <-b.stop:
+			b.drain(eventCh)
+			b.shutdown()
+			return
+		}
+	}
+}
+
+// drain captures the events already queued on the channel.
+func (b *Backend) drain(eventCh <-chan glog.Event) {
+	for {
+		select {
+		case e, ok := <-eventCh:
+			if !ok {
+				return
+			}
+			b.capture(e)
+		default:
+			return
+		}
+	}
+}
+
+// capture sends the event if the rate limits allow it. FATAL events, which
+// glog itself never passes to backends, are flushed immediately, as the
+// process is about to exit.
+func (b *Backend) capture(e glog.Event) {
+	if !b.rl.allow(e.Severity) {
+		atomic.AddUint64(&b.rateLimited, 1)
+		return
+	}
+	_ = b.send(buildMessage(b.host, b.attrs, e, b.o), e.Severity == "FATAL")
+}
+
+func (b *Backend) reportDrops() {
+	for _, m := range b.rl.dropReports(b.host) {
+		_ = b.send(m, false)
+	}
+}
+
+// shutdown sends the final drop reports, and closes the writer, which sends
+// any buffered messages.
+func (b *Backend) shutdown() {
+	b.reportDrops()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.closeErr = b.w.Close()
+}
+
+// send writes the message, and if flush is set, sends any buffered messages.
+func (b *Backend) send(m *Message, flush bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		atomic.AddUint64(&b.failed, 1)
+		return fmt.Errorf("backend is closed")
+	}
+
+	err := b.w.WriteMessage(m)
+	if f, ok := b.w.(flusher); ok && flush {
+		// A ReconnectingWriter keeps the messages it fails to write, so
+		// only the result of flushing them matters.
+		if ferr := f.Flush(); ferr != nil || isReconnecting(b.w) {
+			err = ferr
+		}
+	}
+	if _, ok := b.w.(deliveryCounter); !ok {
+		if err != nil {
+			atomic.AddUint64(&b.failed, 1)
+		} else {
+			atomic.AddUint64(&b.sent, 1)
+		}
+	} else if errors.As(err, new(encodeError)) {
+		// The writer never saw the message, so it isn't counted by it.
+		atomic.AddUint64(&b.failed, 1)
+	}
+	return err
+}
+
+func isReconnecting(w Writer) bool {
+	_, ok := w.(*ReconnectingWriter)
+	return ok
+}
+
+// Send sends the event synchronously, bypassing the event channel and the
+// rate limits, and waits for any buffered messages to be sent. It can be
+// used for the last messages logged before the process exits, which could
+// otherwise be lost.
+func (b *Backend) Send(e glog.Event) error {
+	return b.send(buildMessage(b.host, b.attrs, e, b.o), true)
+}
+
+// Fatal sends a FATAL event with Send, then logs the arguments with
+// glog.Fatal, which exits the process. glog exits as soon as a FATAL message
+// is logged, before it is passed to any backends, so FATAL events can only
+// be sent this way. Errors among the arguments are added as fields.
+func (b *Backend) Fatal(args ...interface{}) {
+	_ = b.Send(fatalEvent(fmt.Sprint(args...), args))
+	glog.FatalWithDepth(1, args...)
+}
+
+// Fatalf is Fatal with a format string, logged with glog.Fatalf.
+func (b *Backend) Fatalf(format string, args ...interface{}) {
+	e := fatalEvent(fmt.Sprintf(format, args...), args)
+	e.Data = append(e.Data, glog.FormatStringArg{Format: format})
+	_ = b.Send(e)
+	glog.FatalfWithDepth(1, format, args...)
+}
+
+// fatalEvent must be called directly by Fatal or Fatalf, since the stack
+// trace of the event starts at their caller.
+func fatalEvent(message string, args []interface{}) glog.Event {
+	callers := make([]uintptr, 64)
+	e := glog.Event{
+		Severity:   "FATAL",
+		Message:    []byte(message),
+		StackTrace: callers[:runtime.Callers(3, callers)],
+	}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			e.Data = append(e.Data, glog.ErrorArg{Error: err})
+		}
+	}
+	return e
+}
+
+// Stats returns statistics of the messages sent by the backend.
+func (b *Backend) Stats() Stats {
+	s := Stats{
+		Sent:        atomic.LoadUint64(&b.sent),
+		RateLimited: atomic.LoadUint64(&b.rateLimited),
+		Failed:      atomic.LoadUint64(&b.failed),
+	}
+	if c, ok := b.w.(deliveryCounter); ok {
+		sent, failed, buffered := c.delivered()
+		s.Sent += sent
+		s.Failed += failed
+		s.Buffered = buffered
+	}
+	return s
+}
+
+// Close stops receiving events, sends the events already queued on the
+// channel and any buffered messages, and closes the connection. It returns
+// an error if any messages could not be sent. If the context is done
+// first, the context's error is returned, and the remaining messages are
+// sent in the background.
+func (b *Backend) Close(ctx context.Context) error {
+	b.stopOnce.Do(func() { close(b.stop) })
+	select {
+	case <-b.done:
+		return b.closeErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
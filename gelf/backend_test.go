@@ -0,0 +1,123 @@
+package gelf_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+func TestBackendClose(t *testing.T) {
+	input := &httpInput{}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	// The events are still queued, and the batch is not full, when the
+	// backend is closed
+	events := make(chan glog.Event, 3)
+	for _, m := range []string{"one", "two", "three"} {
+		events <- glog.Event{Severity: "INFO", Message: []byte(m)}
+	}
+	b, err := gelf.Start(nil, server.URL+"?batch_size=10&flush_interval=1h", 0, events)
+	require.NoError(t, err)
+	require.NoError(t, b.Close(context.Background()))
+
+	received := input.received()
+	if assert.Len(t, received, 1) {
+		assert.Contains(t, received[0], `"short_message":"one"`)
+		assert.Contains(t, received[0], `"short_message":"three"`)
+	}
+	assert.Equal(t, gelf.Stats{Sent: 3}, b.Stats())
+
+	assert.Error(t, b.Send(glog.Event{Severity: "INFO", Message: []byte("closed")}))
+	assert.Equal(t, uint64(1), b.Stats().Failed)
+}
+
+func TestBackendCloseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	events := make(chan glog.Event, 1)
+	events <- glog.Event{Severity: "INFO", Message: []byte("slow")}
+	b, err := gelf.Start(nil, server.URL, 0, events)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, b.Close(ctx))
+}
+
+func TestBackendFatal(t *testing.T) {
+	input := &httpInput{}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	events := make(chan glog.Event, 2)
+	b, err := gelf.Start(nil, server.URL+"?batch_size=10&flush_interval=1h", 0, events)
+	require.NoError(t, err)
+	defer b.Close(context.Background())
+
+	// FATAL events are sent without waiting for the batch to fill
+	events <- glog.Event{Severity: "INFO", Message: []byte("starting")}
+	events <- glog.Event{Severity: "FATAL", Message: []byte("crashed")}
+	assert.Eventually(t, func() bool { return len(input.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Events sent directly are flushed before Send returns
+	require.NoError(t, b.Send(glog.Event{Severity: "ERROR", Message: []byte("last words")}))
+	received := input.received()
+	if assert.Len(t, received, 2) {
+		assert.Contains(t, received[0], `"short_message":"crashed"`)
+		assert.Contains(t, received[1], `"short_message":"last words"`)
+	}
+	assert.Equal(t, uint64(3), b.Stats().Sent)
+}
+
+func TestBackendStats(t *testing.T) {
+	input := &httpInput{failures: 1}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	events := make(chan glog.Event, 3)
+	for i := 0; i < 3; i++ {
+		events <- glog.Event{Severity: "WARNING", Message: []byte("flood")}
+	}
+	close(events)
+
+	// The first request fails without being retried, and the other events
+	// are rate limited, leaving only the report of dropped events
+	b, err := gelf.Start(nil, server.URL+"?retries=0", 1, events)
+	require.NoError(t, err)
+	assert.Error(t, b.Close(context.Background()), "the failure is reported")
+	assert.Equal(t, gelf.Stats{Sent: 1, RateLimited: 2, Failed: 1}, b.Stats())
+
+	received := input.received()
+	if assert.Len(t, received, 1) {
+		assert.Contains(t, received[0], `"short_message":"dropped 2 events at level WARNING"`)
+	}
+}
+
+func TestBackendEncodeFailures(t *testing.T) {
+	input := &httpInput{}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	// Messages which can't be encoded are counted as failed, although the
+	// writer counts the messages it sends itself
+	w, err := gelf.Dial(server.URL + "?batch_size=10&flush_interval=1h")
+	require.NoError(t, err)
+	b := gelf.NewTestBackend(w)
+	assert.Error(t, b.SendMessage(&gelf.Message{Host: "web-1"}))
+	require.NoError(t, b.Send(glog.Event{Severity: "INFO", Message: []byte("valid")}))
+	assert.Equal(t, gelf.Stats{Sent: 1, Failed: 1}, b.Stats())
+}
@@ -0,0 +1,11 @@
+package gelf
+
+// NewTestBackend returns a backend writing to w.
+func NewTestBackend(w Writer) *Backend {
+	return &Backend{host: "test", w: w}
+}
+
+// SendMessage sends a message which may not be built from a glog event.
+func (b *Backend) SendMessage(m *Message) error {
+	return b.send(m, false)
+}
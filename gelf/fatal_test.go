@@ -0,0 +1,56 @@
+package gelf_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+// fatalURLEnv is set to the URL of an HTTP input when the test binary is run
+// by TestBackendFatalf, to call Fatalf in a process which can exit.
+const fatalURLEnv = "GELF_TEST_FATAL_URL"
+
+func TestBackendFatalf(t *testing.T) {
+	if url := os.Getenv(fatalURLEnv); url != "" {
+		runFatalf(url)
+		return
+	}
+
+	input := &httpInput{}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestBackendFatalf$")
+	cmd.Env = append(os.Environ(), fatalURLEnv+"="+server.URL)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	require.True(t, errors.As(err, &exit), "the process exits: %v\n%s", err, out)
+	assert.Equal(t, 255, exit.ExitCode(), "glog.Fatal exits with 255")
+	assert.Contains(t, string(out), "unable to start: connection refused", "the message is logged")
+
+	// The event is sent before exiting, without waiting for the batch to fill
+	received := input.received()
+	if assert.Len(t, received, 1) {
+		assert.Contains(t, received[0], `"short_message":"unable to start: connection refused"`)
+		assert.Contains(t, received[0], `"level":2`)
+		assert.Contains(t, received[0], `"_errorMessage":"connection refused"`)
+		assert.Contains(t, received[0], `"_groupingKey":"unable to start"`)
+		assert.Contains(t, received[0], `"_function":"github.com/yext/glog-contrib/gelf_test.runFatalf"`)
+	}
+}
+
+// runFatalf sends a FATAL event to the HTTP input with Fatalf, and exits.
+func runFatalf(url string) {
+	b, err := gelf.Start(nil, url+"?batch_size=10&flush_interval=1h", 0, make(chan glog.Event))
+	if err != nil {
+		panic(err)
+	}
+	b.Fatalf("unable to start: %v", errors.New("connection refused"))
+}
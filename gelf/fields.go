@@ -0,0 +1,78 @@
+package gelf
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/internal/errfmt"
+	"github.com/yext/glog-contrib/internal/formatstring"
+	errstack "github.com/yext/glog-contrib/stacktrace"
+)
+
+// The maximum number of wrapped errors added to messages.
+const maxErrorDepth = 10
+
+// structuredFields adds fields describing the errors, format string, call
+// site and HTTP requests of the event, so that they can be searched.
+func structuredFields(data map[string]interface{}, e glog.Event) {
+	for _, d := range e.Data {
+		switch t := d.(type) {
+		case glog.ErrorArg:
+			errorFields(data, t.Error)
+		case glog.FormatStringArg:
+			data["_format"] = t.Format
+			data["_groupingKey"] = formatstring.Cleanup(t.Format)
+		case *http.Request:
+			requestFields(data, t)
+		case *http.Response:
+			if t.Request != nil {
+				requestFields(data, t.Request)
+			}
+			data["_httpStatus"] = t.StatusCode
+		}
+	}
+
+	if len(e.StackTrace) > 0 {
+		st := errstack.ExtractFrames(e.StackTrace, nil)
+		if n := len(st.Frames); n > 0 {
+			// Frames are ordered from outermost to innermost.
+			f := st.Frames[n-1]
+			data["_file"] = f.Filename
+			data["_line"] = f.Lineno
+			data["_function"] = errfmt.Function(f)
+		}
+	}
+}
+
+// errorFields adds the message, type and stack trace of each error in the
+// chain, from the outermost to the root cause, as _error0Message,
+// _error0Type, _error0Stack, _error1Message and so on.
+func errorFields(data map[string]interface{}, err error) {
+	if err == nil {
+		return
+	}
+	data["_errorMessage"] = err.Error()
+	data["_errorType"] = fmt.Sprintf("%T", err)
+
+	chain := errfmt.Chain(err, maxErrorDepth)
+	for i, err := range chain {
+		data[fmt.Sprintf("_error%dMessage", i)] = err.Error()
+		data[fmt.Sprintf("_error%dType", i)] = fmt.Sprintf("%T", err)
+		if st := errstack.ExtractStacktrace(err); st != nil && len(st.Frames) > 0 {
+			data[fmt.Sprintf("_error%dStack", i)] = errfmt.Stack(st)
+		}
+		data["_errorRootCause"] = err.Error()
+	}
+	data["_errorCount"] = len(chain)
+}
+
+func requestFields(data map[string]interface{}, r *http.Request) {
+	data["_httpMethod"] = r.Method
+	if r.URL != nil {
+		data["_httpPath"] = r.URL.Path
+	}
+	if r.Host != "" {
+		data["_httpHost"] = r.Host
+	}
+}
@@ -0,0 +1,79 @@
+package gelf_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/gelf"
+	"golang.org/x/xerrors"
+)
+
+// captureMessage sends the event with Capture, and returns the fields of
+// the message received.
+func captureMessage(t *testing.T, e glog.Event) map[string]interface{} {
+	server := newUDPServer(t)
+	ch := make(chan glog.Event, 1)
+	ch <- e
+	close(ch)
+	require.NoError(t, gelf.Capture(nil, server.uri(""), 0, ch))
+	data, _ := server.receive(t)
+	return decompress(t, data)
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 20)
+	return pcs[:runtime.Callers(2, pcs)]
+}
+
+func openConfig() error {
+	_, err := os.Open("/does/not/exist")
+	return xerrors.Errorf("opening config: %w", err)
+}
+
+func TestStructuredFields(t *testing.T) {
+	err := openConfig()
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/api/users?id=1", nil)
+	e := glog.Event{
+		Severity:   "ERROR",
+		Message:    []byte("loading failed"),
+		StackTrace: callers(),
+		Data: []interface{}{
+			glog.ErrorArg{Error: err},
+			glog.FormatStringArg{Format: "loading %s failed: %v"},
+			&http.Response{StatusCode: http.StatusBadGateway, Request: r},
+		},
+	}
+
+	m := captureMessage(t, e)
+	assert.Equal(t, "opening config: open /does/not/exist: no such file or directory", m["_errorMessage"])
+	assert.Equal(t, "*xerrors.wrapError", m["_errorType"])
+	assert.Equal(t, "no such file or directory", m["_errorRootCause"])
+	assert.Equal(t, float64(3), m["_errorCount"])
+	assert.Equal(t, "*fs.PathError", m["_error1Type"])
+	assert.Equal(t, "syscall.Errno", m["_error2Type"])
+	if assert.Contains(t, m, "_error0Stack") {
+		assert.True(t, strings.HasPrefix(m["_error0Stack"].(string), "github.com/yext/glog-contrib/gelf_test.openConfig\n\t"),
+			m["_error0Stack"])
+	}
+
+	assert.Equal(t, "loading %s failed: %v", m["_format"])
+	assert.Equal(t, "loading failed", m["_groupingKey"])
+
+	assert.Equal(t, "POST", m["_httpMethod"])
+	assert.Equal(t, "/api/users", m["_httpPath"])
+	assert.Equal(t, "example.com", m["_httpHost"])
+	assert.Equal(t, float64(502), m["_httpStatus"])
+
+	assert.Equal(t, "github.com/yext/glog-contrib/gelf_test.TestStructuredFields", m["_function"])
+	assert.True(t, strings.HasSuffix(m["_file"].(string), "gelf/fields_test.go"), m["_file"])
+	assert.NotZero(t, m["_line"])
+	assert.Regexp(t, `^function github.com/yext/glog-contrib/gelf_test.TestStructuredFields at line \d+$`,
+		m["_exceptionStackTrace"], "runtime and testing frames are omitted")
+}
@@ -1,80 +1,117 @@
 package gelf
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/aphistic/golf"
 	"github.com/yext/glog"
-	"github.com/yext/glog-contrib/raven/stacktrace"
-
-	"golang.org/x/time/rate"
+	"github.com/yext/glog-contrib/internal/errfmt"
+	"github.com/yext/glog-contrib/internal/glogheader"
+	errstack "github.com/yext/glog-contrib/stacktrace"
 )
 
 // Capture events and sends them to the gelf server.
-// Events sent at a higher rate than maxEventsPerSec will be ignored.
-// The uri must have a udp or tcp scheme.
-func Capture(attrs map[string]interface{}, serverUri string, maxEventsPerSec int, eventCh <-chan glog.Event) error {
-	c, _ := golf.NewClient()
-	defer c.Close()
-
-	if err := c.Dial(serverUri); err != nil {
-		return err
-	}
-	logger, err := c.NewLogger()
+// Events sent at a higher rate than maxEventsPerSec will be ignored, except
+// FATAL events, and the number ignored is reported periodically. Each
+// severity is limited separately, so that a flood of INFO events does not
+// cause errors to be dropped. If maxEventsPerSec is zero or less, the rate is
+// unlimited.
+// The uri must have a udp, tcp, tls, http or https scheme (see Dial).
+// Capture returns once the channel is closed and any buffered messages
+// have been sent; use Start to be able to close it earlier.
+func Capture(attrs map[string]interface{}, serverUri string, maxEventsPerSec int, eventCh <-chan glog.Event, options ...Option) error {
+	b, err := Start(attrs, serverUri, maxEventsPerSec, eventCh, options...)
 	if err != nil {
 		return err
 	}
+	<-b.done
+	return nil
+}
 
-	for k, v := range attrs {
-		logger.SetAttr(k, v)
-	}
+var levels = map[string]int{
+	"INFO":    LevelInfo,
+	"WARNING": LevelWarning,
+	"ERROR":   LevelError,
+	"FATAL":   LevelCritical,
+}
 
-	// Also use maxEventsPerSec as the burst size
-	var (
-		limit = rate.Every(time.Second / time.Duration(maxEventsPerSec))
-		rl    = rate.NewLimiter(limit, maxEventsPerSec)
-	)
-	for e := range eventCh {
-		if !rl.Allow() {
-			continue
-		}
+// buildMessage converts a glog event into a GELF message. Fields set with
+// attributes take precedence over data maps and fields extracted from
+// contexts, then the structured fields and the attributes passed to Capture.
+func buildMessage(host string, attrs map[string]interface{}, e glog.Event, o Options) *Message {
+	now := time.Now()
+	data := map[string]interface{}{}
+	addFields(data, attrs)
 
-		logEvent(logger, e)
+	// The timestamp, file and line are taken from the glog header, if
+	// present. The file and line are replaced by the call site, which
+	// includes the path of the file, if there is a stack trace.
+	timestamp := now
+	header, message, ok := glogheader.Parse(string(e.Message), now)
+	if ok {
+		timestamp = header.Time
+		data["_file"] = header.File
+		data["_line"] = header.Line
+		if header.ThreadID != 0 {
+			data["_threadId"] = header.ThreadID
+		}
 	}
-	return nil
-}
+	structuredFields(data, e)
 
-func logEvent(logger *golf.Logger, e glog.Event) {
-	data := map[string]interface{}{}
+	fields := map[string]interface{}{}
 	for _, d := range e.Data {
 		switch t := d.(type) {
 		case map[string]interface{}:
-			for k, v := range t {
-				data[k] = v
+			addFields(data, t)
+		case context.Context:
+			for _, extract := range o.ContextExtractors {
+				addFields(data, extract(t))
 			}
+		case field:
+			fields[t.key] = t.value
+		case facility:
+			fields["facility"] = string(t)
+		case stream:
+			fields["stream"] = string(t)
 		}
 	}
+	addFields(data, fields)
 
-	st := stacktrace.Build(e.StackTrace)
 	var frames []string
-	for _, frame := range st.Frames {
-		frames = append(frames, fmt.Sprintf("function %s at line %s", frame.Function, frame.LineNo))
+	if st := errstack.ExtractFrames(e.StackTrace, nil); st != nil {
+		for _, frame := range st.Frames {
+			frames = append(frames, fmt.Sprintf("function %s at line %d", errfmt.Function(frame), frame.Lineno))
+		}
 	}
-	data["exceptionStackTrace"] = strings.Join(frames, ", ")
+	data["_exceptionStackTrace"] = strings.Join(frames, ", ")
 
-	message := string(e.Message)
+	data["_levelName"] = e.Severity
 
-	data["levelName"] = e.Severity
-	switch e.Severity {
-	case "INFO":
-		logger.Infom(data, message)
-	case "WARNING":
-		logger.Warnm(data, message)
-	case "ERROR":
-		logger.Errm(data, message)
-	case "FATAL":
-		logger.Critm(data, message)
+	level, ok := levels[e.Severity]
+	if !ok {
+		level = LevelInfo
 	}
+
+	short, full := splitMessage(message)
+	if strings.TrimSpace(short) == "" {
+		short = "(empty)"
+	}
+	return &Message{
+		Host:         host,
+		ShortMessage: short,
+		FullMessage:  full,
+		Timestamp:    timestamp,
+		Level:        level,
+		Extra:        data,
+	}
+}
+
+// splitMessage splits a message into its first line, used as the short
+// message, and the remaining lines, used as the full message.
+func splitMessage(msg string) (string, string) {
+	msg = strings.TrimRight(msg, "\r\n")
+	short, full, _ := strings.Cut(msg, "\n")
+	return strings.TrimRight(short, "\r"), full
 }
@@ -0,0 +1,39 @@
+package gelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+)
+
+func TestGlogHeader(t *testing.T) {
+	now := time.Now()
+	logged := time.Date(now.Year(), 1, 2, 15, 4, 5, 678901000, time.Local)
+	if logged.After(now.Add(24 * time.Hour)) {
+		logged = logged.AddDate(-1, 0, 0)
+	}
+
+	m := captureMessage(t, glog.Event{Severity: "ERROR",
+		Message: []byte("E0102 15:04:05.678901 1234 server.go:42] request failed\ngoroutine 1 [running]:\nmain.main()\n")})
+	assert.Equal(t, "request failed", m["short_message"])
+	assert.Equal(t, "goroutine 1 [running]:\nmain.main()", m["full_message"])
+	assert.InDelta(t, float64(logged.UnixNano())/1e9, m["timestamp"], 1e-3)
+	assert.Equal(t, float64(1234), m["_threadId"])
+	assert.Equal(t, "server.go", m["_file"])
+	assert.Equal(t, float64(42), m["_line"])
+
+	// The thread ID is optional
+	m = captureMessage(t, glog.Event{Severity: "INFO", Message: []byte("I0102 15:04:05.678901 server.go:7] started\n")})
+	assert.Equal(t, "started", m["short_message"])
+	assert.NotContains(t, m, "full_message")
+	assert.NotContains(t, m, "_threadId")
+	assert.Equal(t, float64(7), m["_line"])
+
+	// Messages without a header are sent as they are, at the current time
+	m = captureMessage(t, glog.Event{Severity: "INFO", Message: []byte("no header\n")})
+	assert.Equal(t, "no header", m["short_message"])
+	assert.NotContains(t, m, "_file")
+	assert.InDelta(t, float64(time.Now().UnixNano())/1e9, m["timestamp"], 5)
+}
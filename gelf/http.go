@@ -0,0 +1,268 @@
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPConfig configures how messages are sent to a GELF HTTP input.
+type HTTPConfig struct {
+	// BatchSize is the number of messages sent in each request, separated
+	// by newlines. Batches of more than one message require bulk receiving
+	// to be enabled on the input. Defaults to 1.
+	BatchSize int
+	// FlushInterval is the longest time messages wait to be sent while the
+	// batch is not full. Defaults to 1 second.
+	FlushInterval time.Duration
+	// Retries is the number of times failed requests are retried, with
+	// exponential backoff. Requests are retried on network errors, 429 and
+	// 5xx responses. Defaults to 3.
+	Retries int
+	// Compression of request bodies. Defaults to CompressNone.
+	Compression Compression
+	// BufferSize is the maximum number of messages waiting to be sent.
+	// When it is full, the oldest messages are dropped. Defaults to 1000,
+	// or BatchSize if larger.
+	BufferSize int
+}
+
+// The backoff before the first retry of a request, doubled for each retry.
+var (
+	retryBackoff    = 100 * time.Millisecond
+	maxRetryBackoff = 5 * time.Second
+)
+
+// HTTPWriter sends messages to a GELF HTTP input, such as
+// "https://graylog:12201/gelf". Messages are sent in the background, so
+// that writing them never waits for a request.
+type HTTPWriter struct {
+	endpoint string
+	client   *http.Client
+	config   HTTPConfig
+
+	mu     sync.Mutex
+	batch  [][]byte
+	sent   uint64
+	failed uint64
+	// err is the first error of the messages sent in the background since
+	// the last Flush.
+	err error
+
+	// send serializes requests, so that batches are sent in order.
+	send sync.Mutex
+
+	// full is signalled when a batch is ready to be sent.
+	full     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewHTTPWriter returns a writer which sends messages to the endpoint with
+// the client. Messages are sent in the background once there are enough
+// for a batch, or every FlushInterval, until the writer is closed.
+func NewHTTPWriter(endpoint string, client *http.Client, config HTTPConfig) *HTTPWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+	if config.BufferSize < config.BatchSize {
+		config.BufferSize = config.BatchSize
+	}
+	w := &HTTPWriter{
+		endpoint: endpoint,
+		client:   client,
+		config:   config,
+		full:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.sendLoop()
+	return w
+}
+
+func (w *HTTPWriter) sendLoop() {
+	defer close(w.done)
+	t := time.NewTicker(w.config.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.full:
+			w.sendInBackground(w.config.BatchSize)
+		case <-t.C:
+			w.sendInBackground(1)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// sendInBackground sends batches while at least min messages are
+// buffered, keeping the first error to be returned by Flush.
+func (w *HTTPWriter) sendInBackground(min int) {
+	w.send.Lock()
+	defer w.send.Unlock()
+	if err := w.sendBuffered(min); err != nil {
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.mu.Unlock()
+	}
+}
+
+// WriteMessage adds the message to the batch, to be sent in the
+// background. If the buffer is full, the oldest message is dropped.
+func (w *HTTPWriter) WriteMessage(m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return encodeError{err}
+	}
+	w.mu.Lock()
+	if len(w.batch) >= w.config.BufferSize {
+		w.batch[0] = nil
+		w.batch = w.batch[1:]
+		w.failed++
+		if w.err == nil {
+			w.err = fmt.Errorf("%s: buffer full, dropped messages", w.endpoint)
+		}
+	}
+	w.batch = append(w.batch, data)
+	full := len(w.batch) >= w.config.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush sends the buffered messages, waiting for any requests in
+// progress. It returns an error if any of them, or any messages sent in
+// the background since the last Flush, could not be sent.
+func (w *HTTPWriter) Flush() error {
+	w.send.Lock()
+	defer w.send.Unlock()
+	err := w.sendBuffered(1)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil {
+		err = w.err
+	}
+	w.err = nil
+	return err
+}
+
+// sendBuffered sends batches of messages while at least min are
+// buffered, and returns the first error. w.send must be held.
+func (w *HTTPWriter) sendBuffered(min int) error {
+	var firstErr error
+	for {
+		w.mu.Lock()
+		n := len(w.batch)
+		if n == 0 || n < min {
+			w.mu.Unlock()
+			return firstErr
+		}
+		if n > w.config.BatchSize {
+			n = w.config.BatchSize
+		}
+		batch := w.batch[:n:n]
+		w.batch = w.batch[n:]
+		w.mu.Unlock()
+
+		err := w.post(bytes.Join(batch, []byte("\n")))
+
+		w.mu.Lock()
+		if err != nil {
+			w.failed += uint64(len(batch))
+		} else {
+			w.sent += uint64(len(batch))
+		}
+		w.mu.Unlock()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+}
+
+func (w *HTTPWriter) delivered() (sent, failed uint64, buffered int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sent, w.failed, len(w.batch)
+}
+
+// post sends a request with the body, retrying if it fails.
+func (w *HTTPWriter) post(body []byte) error {
+	body, err := compress(body, w.config.Compression)
+	if err != nil {
+		return err
+	}
+
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.request(body)
+		if err == nil || !retry || attempt == w.config.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// request sends a single request, returning whether it should be retried
+// if it failed.
+func (w *HTTPWriter) request(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch w.config.Compression {
+	case CompressGzip:
+		req.Header.Set("Content-Encoding", "gzip")
+	case CompressZlib:
+		req.Header.Set("Content-Encoding", "deflate")
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s: %s", w.endpoint, resp.Status)
+}
+
+// Close stops sending in the background, then sends any buffered
+// messages. It returns an error if any messages could not be sent since the
+// last Flush.
+func (w *HTTPWriter) Close() error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+	return w.Flush()
+}
@@ -0,0 +1,195 @@
+package gelf_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+// httpInput records the bodies of requests to a GELF HTTP input.
+type httpInput struct {
+	mu       sync.Mutex
+	bodies   []string
+	failures int
+}
+
+func (in *httpInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.failures > 0 {
+		in.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, _ := io.ReadAll(body)
+	in.bodies = append(in.bodies, r.URL.Path+" "+string(b))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (in *httpInput) received() []string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]string(nil), in.bodies...)
+}
+
+func message(short string) *gelf.Message {
+	return &gelf.Message{Host: "web-1", ShortMessage: short}
+}
+
+func TestHTTPWriter(t *testing.T) {
+	input := &httpInput{failures: 1}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	w, err := gelf.Dial(server.URL + "?compress=gzip")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMessage(message("one")))
+	require.NoError(t, w.Close())
+
+	// The first request failed, and was retried
+	received := input.received()
+	if assert.Len(t, received, 1) {
+		assert.True(t, strings.HasPrefix(received[0], `/gelf {"version":"1.1"`), received[0])
+		assert.Contains(t, received[0], `"short_message":"one"`)
+	}
+
+	// Messages are sent in the background, and failures are reported when
+	// the writer is closed
+	input.failures = 10
+	w, err = gelf.Dial(server.URL + "/custom?retries=1")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMessage(message("two")))
+	assert.Error(t, w.Close())
+	assert.Equal(t, 8, input.failures)
+}
+
+func TestHTTPWriterSendsInBackground(t *testing.T) {
+	release := make(chan struct{})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	w, err := gelf.Dial(server.URL + "?buffer_size=2")
+	require.NoError(t, err)
+	// Writing doesn't wait for requests, and drops the oldest messages once
+	// the buffer is full.
+	for _, m := range []string{"one", "two", "three", "four"} {
+		require.NoError(t, w.WriteMessage(message(m)))
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 1 }, 5*time.Second, time.Millisecond)
+	close(release)
+	assert.Error(t, w.Close(), "messages were dropped")
+	assert.LessOrEqual(t, atomic.LoadInt32(&requests), int32(3))
+}
+
+func TestHTTPWriterBatches(t *testing.T) {
+	input := &httpInput{}
+	server := httptest.NewServer(input)
+	defer server.Close()
+
+	w, err := gelf.Dial(server.URL + "/gelf?batch_size=2&flush_interval=1h")
+	require.NoError(t, err)
+	for _, m := range []string{"one", "two", "three"} {
+		require.NoError(t, w.WriteMessage(message(m)))
+	}
+	assert.Eventually(t, func() bool { return len(input.received()) == 1 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, w.Close())
+
+	received := input.received()
+	if assert.Len(t, received, 2) {
+		lines := strings.Split(received[0], "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Contains(t, lines[0], `"short_message":"one"`)
+			assert.Contains(t, lines[1], `"short_message":"two"`)
+		}
+		assert.Contains(t, received[1], `"short_message":"three"`)
+	}
+}
+
+func TestHTTPSWriter(t *testing.T) {
+	input := &httpInput{}
+	server := httptest.NewTLSServer(input)
+	defer server.Close()
+
+	w, err := gelf.Dial(server.URL + "?retries=0")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMessage(message("untrusted")))
+	assert.Error(t, w.Close())
+
+	w, err = gelf.Dial(server.URL + "?ca=" + writeCA(t, server))
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMessage(message("trusted")))
+	require.NoError(t, w.Close())
+	assert.Len(t, input.received(), 1)
+}
+
+func TestTLSWriter(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.StartTLS()
+	defer server.Close()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", server.TLS)
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		m, _ := bufio.NewReader(conn).ReadString(0)
+		received <- m
+	}()
+
+	// The certificate of the test server is valid for example.com
+	uri := "tls://" + l.Addr().String() + "?server_name=example.com&ca=" + writeCA(t, server)
+	w, err := gelf.Dial(uri)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMessage(message("secure")))
+	m := <-received
+	assert.Contains(t, m, `"short_message":"secure"`)
+	assert.True(t, strings.HasSuffix(m, "\x00"))
+	w.Close()
+
+	_, err = gelf.Dial("tls://" + l.Addr().String() + "?ca=/does/not/exist")
+	assert.Error(t, err)
+	_, err = gelf.Dial("tls://" + l.Addr().String() + "?cert=client.pem")
+	assert.Error(t, err)
+}
+
+// writeCA writes the certificate of the test server to a file.
+func writeCA(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, b, 0600))
+	return path
+}
@@ -0,0 +1,186 @@
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the version of GELF implemented by this package.
+const Version = "1.1"
+
+// Levels of GELF messages, which are the syslog severities.
+const (
+	LevelEmergency = 0
+	LevelAlert     = 1
+	LevelCritical  = 2
+	LevelError     = 3
+	LevelWarning   = 4
+	LevelNotice    = 5
+	LevelInfo      = 6
+	LevelDebug     = 7
+)
+
+// Message is a GELF message.
+type Message struct {
+	// Host is the name of the host which sent the message.
+	Host string
+	// ShortMessage is a short descriptive message.
+	ShortMessage string
+	// FullMessage is a long message, which can contain a backtrace.
+	FullMessage string
+	// Timestamp is the time the message was logged. Defaults to the time it
+	// is encoded.
+	Timestamp time.Time
+	// Level is the syslog level of the message.
+	Level int
+	// Extra contains additional fields. The names are prefixed with an
+	// underscore when encoded, if they do not already start with one.
+	// Values which are not strings or numbers are encoded as JSON strings.
+	Extra map[string]interface{}
+}
+
+// Additional field names must match this, once prefixed with an underscore.
+var fieldNameRe = regexp.MustCompile(`^_[\w.\-]+$`)
+
+// fieldName returns the name of an additional field, prefixed with an
+// underscore, or an error if it is not allowed by the GELF specification.
+func fieldName(name string) (string, error) {
+	if !strings.HasPrefix(name, "_") {
+		name = "_" + name
+	}
+	if !fieldNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid additional field name %q", name)
+	}
+	if name == "_id" {
+		return "", fmt.Errorf("reserved additional field name %q", name)
+	}
+	return name, nil
+}
+
+// SanitizeFieldName converts a name into an allowed additional field name,
+// prefixed with an underscore, by replacing invalid characters with
+// underscores and renaming the reserved "_id" field to "_id_".
+func SanitizeFieldName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if !strings.HasPrefix(name, "_") {
+		name = "_" + name
+	}
+	switch name {
+	case "_":
+		return "_empty"
+	case "_id":
+		return "_id_"
+	}
+	return name
+}
+
+// fieldValue converts an additional field value into a string or number.
+func fieldValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string, json.Number,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64:
+		return t
+	case float32:
+		return fieldValue(float64(t))
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return fmt.Sprint(t)
+		}
+		return t
+	case nil:
+		return ""
+	case error:
+		return t.Error()
+	case fmt.Stringer:
+		return t.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// formatTimestamp formats a time as seconds since the epoch, with
+// microsecond precision.
+func formatTimestamp(t time.Time) string {
+	usec := t.UnixNano() / int64(time.Microsecond)
+	return fmt.Sprintf("%d.%06d", usec/1e6, usec%1e6)
+}
+
+// MarshalJSON encodes the message as GELF. It returns an error if the
+// message has no host or short message, or an additional field name is
+// invalid or reserved.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	if m.Host == "" {
+		return nil, fmt.Errorf("host must be specified")
+	}
+	if m.ShortMessage == "" {
+		return nil, fmt.Errorf("short message must be specified")
+	}
+	ts := m.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	var buf bytes.Buffer
+	field := func(name string, value interface{}) error {
+		if buf.Len() == 0 {
+			buf.WriteByte('{')
+		} else {
+			buf.WriteByte(',')
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		fmt.Fprintf(&buf, "%q:", name)
+		buf.Write(b)
+		return nil
+	}
+
+	field("version", Version)
+	field("host", m.Host)
+	field("short_message", m.ShortMessage)
+	if m.FullMessage != "" {
+		field("full_message", m.FullMessage)
+	}
+	field("timestamp", json.Number(formatTimestamp(ts)))
+	field("level", m.Level)
+
+	names := make([]string, 0, len(m.Extra))
+	for name := range m.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		n, err := fieldName(name)
+		if err != nil {
+			return nil, err
+		}
+		if seen[n] {
+			return nil, fmt.Errorf("duplicate additional field name %q", n)
+		}
+		seen[n] = true
+		if err := field(n, fieldValue(m.Extra[name])); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,60 @@
+package gelf_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+func TestMessageMarshalJSON(t *testing.T) {
+	m := &gelf.Message{
+		Host:         "web-1",
+		ShortMessage: "short",
+		FullMessage:  "full\nmessage",
+		Timestamp:    time.Unix(1700000000, 123456789),
+		Level:        gelf.LevelError,
+		Extra: map[string]interface{}{
+			"user":   "alice",
+			"_count": 3,
+			"ratio":  0.5,
+			"ok":     true,
+			"tags":   []string{"a", "b"},
+			"err":    errors.New("failed"),
+			"nan":    math.NaN(),
+			"empty":  nil,
+		},
+	}
+	b, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"version":"1.1","host":"web-1","short_message":"short","full_message":"full\nmessage",`+
+		`"timestamp":1700000000.123456,"level":3,"_count":3,"_empty":"","_err":"failed","_nan":"NaN",`+
+		`"_ok":"true","_ratio":0.5,"_tags":"[\"a\",\"b\"]","_user":"alice"}`, string(b))
+}
+
+func TestMessageMarshalJSONErrors(t *testing.T) {
+	for name, m := range map[string]*gelf.Message{
+		"no host":       {ShortMessage: "short"},
+		"no message":    {Host: "web-1"},
+		"reserved":      {Host: "web-1", ShortMessage: "short", Extra: map[string]interface{}{"id": 1}},
+		"invalid":       {Host: "web-1", ShortMessage: "short", Extra: map[string]interface{}{"a b": 1}},
+		"duplicate":     {Host: "web-1", ShortMessage: "short", Extra: map[string]interface{}{"a": 1, "_a": 2}},
+		"empty":         {Host: "web-1", ShortMessage: "short", Extra: map[string]interface{}{"": 1}},
+		"only prefixed": {Host: "web-1", ShortMessage: "short", Extra: map[string]interface{}{"_": 1}},
+	} {
+		_, err := json.Marshal(m)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSanitizeFieldName(t *testing.T) {
+	assert.Equal(t, "_user.name", gelf.SanitizeFieldName("user.name"))
+	assert.Equal(t, "_a_b_c", gelf.SanitizeFieldName("a b/c"))
+	assert.Equal(t, "_id_", gelf.SanitizeFieldName("id"))
+	assert.Equal(t, "_id_", gelf.SanitizeFieldName("_id"))
+	assert.Equal(t, "_empty", gelf.SanitizeFieldName(""))
+}
@@ -0,0 +1,63 @@
+package gelf
+
+import "time"
+
+// Options configures how glog events are sent by Capture.
+type Options struct {
+	// SeverityLimits overrides the maximum number of events sent per
+	// second at a severity, which otherwise defaults to the maxEventsPerSec
+	// argument of Capture. Severities are case-insensitive, and Capture
+	// fails for unknown ones. Limits of zero or less are unlimited.
+	SeverityLimits map[string]int
+	// AlwaysSend is the lowest severity of events which are never rate
+	// limited. Defaults to FATAL.
+	AlwaysSend string
+	// DropReportInterval is how often a message reporting the number of
+	// events dropped by the rate limits is sent. Defaults to 1 minute.
+	DropReportInterval time.Duration
+	// ContextExtractors add fields from contexts passed as glog attributes.
+	ContextExtractors []ContextExtractor
+}
+
+// Option overrides one of the Options.
+type Option func(*Options)
+
+// WithSeverityLimit sets the maximum number of events sent per second at
+// a severity, such as "INFO". A limit of zero or less is unlimited.
+func WithSeverityLimit(severity string, maxEventsPerSec int) Option {
+	return func(o *Options) {
+		if o.SeverityLimits == nil {
+			o.SeverityLimits = map[string]int{}
+		}
+		o.SeverityLimits[severity] = maxEventsPerSec
+	}
+}
+
+// WithAlwaysSend sets Options.AlwaysSend.
+func WithAlwaysSend(severity string) Option {
+	return func(o *Options) { o.AlwaysSend = severity }
+}
+
+// WithDropReportInterval sets Options.DropReportInterval.
+func WithDropReportInterval(interval time.Duration) Option {
+	return func(o *Options) { o.DropReportInterval = interval }
+}
+
+// WithContextExtractors adds to Options.ContextExtractors.
+func WithContextExtractors(extractors ...ContextExtractor) Option {
+	return func(o *Options) { o.ContextExtractors = append(o.ContextExtractors, extractors...) }
+}
+
+func buildOptions(options []Option) Options {
+	o := Options{
+		AlwaysSend:         "FATAL",
+		DropReportInterval: time.Minute,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.DropReportInterval <= 0 {
+		o.DropReportInterval = time.Minute
+	}
+	return o
+}
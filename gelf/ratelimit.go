@@ -0,0 +1,104 @@
+package gelf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiter limits the rate of events separately for each severity, so that
+// a flood of events at one severity does not cause others to be dropped.
+type limiter struct {
+	limits     map[string]rate.Limit
+	alwaysSend int
+	limiters   map[string]*rate.Limiter
+	dropped    map[string]uint64
+}
+
+// newLimiter gives each severity a budget of maxEventsPerSec, unless it has
+// its own limit in the options. Severities are case-insensitive, and
+// unknown ones are rejected.
+func newLimiter(maxEventsPerSec int, o Options) (*limiter, error) {
+	l := &limiter{
+		limits:     map[string]rate.Limit{},
+		alwaysSend: -1,
+		limiters:   map[string]*rate.Limiter{},
+		dropped:    map[string]uint64{},
+	}
+	if o.AlwaysSend != "" {
+		level, ok := levels[strings.ToUpper(o.AlwaysSend)]
+		if !ok {
+			return nil, fmt.Errorf("unknown severity %q", o.AlwaysSend)
+		}
+		l.alwaysSend = level
+	}
+	for severity := range levels {
+		l.limits[severity] = rate.Limit(maxEventsPerSec)
+	}
+	for severity, limit := range o.SeverityLimits {
+		if _, ok := levels[strings.ToUpper(severity)]; !ok {
+			return nil, fmt.Errorf("unknown severity %q", severity)
+		}
+		l.limits[strings.ToUpper(severity)] = rate.Limit(limit)
+	}
+	return l, nil
+}
+
+// allow returns whether an event at the severity can be sent, counting it
+// as dropped if not.
+func (l *limiter) allow(severity string) bool {
+	if level, ok := levels[severity]; ok && level <= l.alwaysSend {
+		return true
+	}
+	limit := l.limits[severity]
+	if limit <= 0 {
+		return true
+	}
+	rl, ok := l.limiters[severity]
+	if !ok {
+		// Also use the limit as the burst size, allowing at least one event
+		// when the limit is less than one per second.
+		burst := int(limit)
+		if burst < 1 {
+			burst = 1
+		}
+		rl = rate.NewLimiter(limit, burst)
+		l.limiters[severity] = rl
+	}
+	if rl.Allow() {
+		return true
+	}
+	l.dropped[severity]++
+	return false
+}
+
+// dropReports returns messages reporting the number of events dropped at
+// each severity since the last report.
+func (l *limiter) dropReports(host string) []*Message {
+	severities := make([]string, 0, len(l.dropped))
+	for severity := range l.dropped {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+
+	var reports []*Message
+	for _, severity := range severities {
+		n := l.dropped[severity]
+		reports = append(reports, &Message{
+			Host:         host,
+			ShortMessage: fmt.Sprintf("dropped %d events at level %s", n, severity),
+			Timestamp:    time.Now(),
+			Level:        LevelWarning,
+			Extra: map[string]interface{}{
+				"_levelName":     "WARNING",
+				"_droppedEvents": n,
+				"_droppedLevel":  severity,
+			},
+		})
+		delete(l.dropped, severity)
+	}
+	return reports
+}
@@ -0,0 +1,124 @@
+package gelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+// capture sends the events with Capture, and returns the short messages
+// received.
+func capture(t *testing.T, n int, maxEventsPerSec int, events []glog.Event, options ...gelf.Option) []string {
+	server := newUDPServer(t)
+	ch := make(chan glog.Event, len(events))
+	for _, e := range events {
+		ch <- e
+	}
+	close(ch)
+	require.NoError(t, gelf.Capture(nil, server.uri(""), maxEventsPerSec, ch, options...))
+
+	var messages []string
+	for i := 0; i < n; i++ {
+		data, _ := server.receive(t)
+		messages = append(messages, decompress(t, data)["short_message"].(string))
+	}
+	return messages
+}
+
+func events(severity string, n int) []glog.Event {
+	var events []glog.Event
+	for i := 0; i < n; i++ {
+		events = append(events, glog.Event{Severity: severity, Message: []byte(severity)})
+	}
+	return events
+}
+
+func TestCaptureRateLimits(t *testing.T) {
+	var all []glog.Event
+	all = append(all, events("INFO", 5)...)
+	all = append(all, events("ERROR", 3)...)
+	all = append(all, events("FATAL", 2)...)
+
+	// Each severity is limited separately, and FATAL events are always sent
+	assert.Equal(t, []string{
+		"INFO", "ERROR", "FATAL", "FATAL",
+		"dropped 2 events at level ERROR",
+		"dropped 4 events at level INFO",
+	}, capture(t, 6, 1, all))
+
+	assert.Equal(t, []string{
+		"INFO", "ERROR", "ERROR", "ERROR", "FATAL", "FATAL",
+		"dropped 4 events at level INFO",
+	}, capture(t, 7, 1, all, gelf.WithAlwaysSend("ERROR")))
+
+	assert.Equal(t, []string{
+		"INFO", "INFO", "INFO", "ERROR", "FATAL", "FATAL",
+		"dropped 2 events at level ERROR",
+		"dropped 2 events at level INFO",
+	}, capture(t, 8, 1, all, gelf.WithSeverityLimit("INFO", 3)))
+}
+
+func TestCaptureRateLimitBudgets(t *testing.T) {
+	var all []glog.Event
+	all = append(all, events("INFO", 4)...)
+	all = append(all, events("WARNING", 4)...)
+	all = append(all, events("ERROR", 4)...)
+
+	// Each severity has the whole limit, so errors are not limited more
+	// because other severities are logged
+	assert.Equal(t, []string{
+		"INFO", "INFO", "WARNING", "WARNING", "ERROR", "ERROR",
+		"dropped 2 events at level ERROR",
+		"dropped 2 events at level INFO",
+		"dropped 2 events at level WARNING",
+	}, capture(t, 9, 2, all))
+
+	// Severities with their own limit, which are case-insensitive, are
+	// limited by it instead
+	assert.Equal(t, []string{
+		"INFO", "WARNING", "WARNING", "ERROR", "ERROR", "ERROR",
+		"dropped 1 events at level ERROR",
+		"dropped 3 events at level INFO",
+		"dropped 2 events at level WARNING",
+	}, capture(t, 9, 2, all, gelf.WithSeverityLimit("info", 1), gelf.WithSeverityLimit("Error", 3)))
+}
+
+func TestCaptureUnknownSeverity(t *testing.T) {
+	server := newUDPServer(t)
+	ch := make(chan glog.Event)
+	close(ch)
+	assert.Error(t, gelf.Capture(nil, server.uri(""), 1, ch, gelf.WithSeverityLimit("DEBUG", 1)))
+	assert.Error(t, gelf.Capture(nil, server.uri(""), 1, ch, gelf.WithAlwaysSend("CRITICAL")))
+	assert.NoError(t, gelf.Capture(nil, server.uri(""), 1, ch, gelf.WithAlwaysSend("error")))
+}
+
+func TestCaptureUnlimited(t *testing.T) {
+	assert.Len(t, capture(t, 20, 0, events("INFO", 20)), 20)
+}
+
+func TestCaptureDropReports(t *testing.T) {
+	server := newUDPServer(t)
+	ch := make(chan glog.Event, 3)
+	done := make(chan error)
+	go func() {
+		done <- gelf.Capture(nil, server.uri(""), 1, ch, gelf.WithDropReportInterval(10*time.Millisecond))
+	}()
+	for _, e := range events("INFO", 3) {
+		ch <- e
+	}
+
+	// Drops are reported periodically, without waiting for more events
+	data, _ := server.receive(t)
+	assert.Equal(t, "INFO", decompress(t, data)["short_message"])
+	data, _ = server.receive(t)
+	m := decompress(t, data)
+	assert.Equal(t, "dropped 2 events at level INFO", m["short_message"])
+	assert.Equal(t, float64(2), m["_droppedEvents"])
+
+	close(ch)
+	assert.NoError(t, <-done)
+}
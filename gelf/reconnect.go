@@ -0,0 +1,112 @@
+package gelf
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/yext/glog-contrib/internal/reconnect"
+)
+
+// ReconnectConfig configures how a ReconnectingWriter buffers messages and
+// reconnects.
+type ReconnectConfig struct {
+	// BufferSize is the maximum number of messages buffered while
+	// disconnected. When it is full, the oldest messages are dropped.
+	// Defaults to 1000.
+	BufferSize int
+	// MinBackoff is the time waited before the first attempt to reconnect,
+	// doubled after each failed attempt. Defaults to 100ms.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest time waited between attempts to reconnect.
+	// Defaults to 30s, and is at least MinBackoff.
+	MaxBackoff time.Duration
+	// WriteTimeout is the longest time a write can block before the
+	// connection is considered dead. Defaults to 10s.
+	WriteTimeout time.Duration
+}
+
+// ConnectionStats are statistics of a ReconnectingWriter.
+type ConnectionStats struct {
+	// Connected is whether the writer is currently connected.
+	Connected bool
+	// Sent is the number of messages written to the connection.
+	Sent uint64
+	// Buffered is the number of messages waiting to be sent.
+	Buffered int
+	// Dropped is the number of messages dropped because the buffer was full.
+	Dropped uint64
+	// Failed is the number of messages dropped because writing them failed
+	// for a reason other than the connection.
+	Failed uint64
+	// Reconnects is the number of times the writer has reconnected.
+	Reconnects uint64
+}
+
+// ReconnectingWriter sends null byte terminated messages over a stream
+// connection, such as TCP or TLS. If the connection fails or is closed by
+// the server, messages are buffered while it reconnects in the background,
+// with exponential backoff and jitter.
+type ReconnectingWriter struct {
+	w *reconnect.Writer
+}
+
+// NewReconnectingWriter connects with dial, returning an error if the first
+// connection fails. Buffered messages are retried in the background until
+// the writer is closed.
+func NewReconnectingWriter(dial func() (net.Conn, error), config ReconnectConfig) (*ReconnectingWriter, error) {
+	w, err := reconnect.New(dial, reconnect.Config{
+		BufferSize:   config.BufferSize,
+		MinBackoff:   config.MinBackoff,
+		MaxBackoff:   config.MaxBackoff,
+		WriteTimeout: config.WriteTimeout,
+		// GELF servers never send data.
+		Watch: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ReconnectingWriter{w: w}, nil
+}
+
+// WriteMessage buffers the message, and sends the buffered messages if
+// connected. It returns an error if they can't be sent, in which case they
+// remain buffered until the writer reconnects.
+func (w *ReconnectingWriter) WriteMessage(m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return encodeError{err}
+	}
+	return w.w.Write(append(data, 0))
+}
+
+// Flush sends the buffered messages, reconnecting immediately if not
+// connected, regardless of the backoff.
+func (w *ReconnectingWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Stats returns statistics of the writer.
+func (w *ReconnectingWriter) Stats() ConnectionStats {
+	s := w.w.Stats()
+	return ConnectionStats{
+		Connected:  s.Connected,
+		Sent:       s.Sent,
+		Buffered:   s.Buffered,
+		Dropped:    s.Dropped,
+		Failed:     s.Failed,
+		Reconnects: s.Reconnects,
+	}
+}
+
+// delivered counts the messages not sent before the writer was closed as
+// failed.
+func (w *ReconnectingWriter) delivered() (sent, failed uint64, buffered int) {
+	return w.w.Delivered()
+}
+
+// Close attempts to send any buffered messages, and closes the connection.
+// It returns an error if any messages were not sent.
+func (w *ReconnectingWriter) Close() error {
+	return w.w.Close()
+}
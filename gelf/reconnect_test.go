@@ -0,0 +1,115 @@
+package gelf_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog-contrib/gelf"
+	"github.com/yext/glog-contrib/internal/testserver"
+)
+
+func TestReconnectingWriter(t *testing.T) {
+	server := testserver.NewTCP(t, testserver.NullTerminated)
+	w, err := gelf.NewReconnectingWriter(func() (net.Conn, error) {
+		return net.Dial("tcp", server.Addr)
+	}, gelf.ReconnectConfig{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, w.WriteMessage(message("before")))
+	assert.Contains(t, server.Receive(t), `"short_message":"before"`)
+
+	// The closed connection is detected, and messages are buffered
+	server.Stop()
+	assert.Eventually(t, func() bool { return !w.Stats().Connected }, 5*time.Second, 10*time.Millisecond)
+	assert.Error(t, w.WriteMessage(message("during")))
+	assert.Equal(t, 1, w.Stats().Buffered)
+
+	// Buffered messages are sent once the server restarts
+	server.Start(t)
+	assert.Contains(t, server.Receive(t), `"short_message":"during"`)
+	require.NoError(t, w.WriteMessage(message("after")))
+	assert.Contains(t, server.Receive(t), `"short_message":"after"`)
+
+	stats := w.Stats()
+	assert.True(t, stats.Connected)
+	assert.Equal(t, uint64(1), stats.Reconnects)
+	assert.Zero(t, stats.Dropped)
+}
+
+func TestReconnectingWriterBuffer(t *testing.T) {
+	server := testserver.NewTCP(t, testserver.NullTerminated)
+	w, err := gelf.NewReconnectingWriter(func() (net.Conn, error) {
+		return net.Dial("tcp", server.Addr)
+	}, gelf.ReconnectConfig{BufferSize: 2, MinBackoff: time.Hour})
+	require.NoError(t, err)
+
+	server.Stop()
+	assert.Eventually(t, func() bool { return !w.Stats().Connected }, 5*time.Second, 10*time.Millisecond)
+	for _, m := range []string{"one", "two", "three"} {
+		assert.Error(t, w.WriteMessage(message(m)))
+	}
+	stats := w.Stats()
+	assert.Equal(t, 2, stats.Buffered)
+	assert.Equal(t, uint64(1), stats.Dropped)
+
+	// Closing makes a final attempt to send the buffered messages
+	server.Start(t)
+	require.NoError(t, w.Close())
+	assert.Contains(t, server.Receive(t), `"short_message":"two"`)
+	assert.Contains(t, server.Receive(t), `"short_message":"three"`)
+	assert.Error(t, w.WriteMessage(message("closed")))
+}
+
+func TestReconnectingWriterFlush(t *testing.T) {
+	server := testserver.NewTCP(t, testserver.NullTerminated)
+	w, err := gelf.NewReconnectingWriter(func() (net.Conn, error) {
+		return net.Dial("tcp", server.Addr)
+	}, gelf.ReconnectConfig{MinBackoff: time.Hour})
+	require.NoError(t, err)
+	defer w.Close()
+
+	server.Stop()
+	assert.Eventually(t, func() bool { return !w.Stats().Connected }, 5*time.Second, 10*time.Millisecond)
+	assert.Error(t, w.WriteMessage(message("buffered")))
+
+	// Flushing reconnects without waiting for the backoff
+	server.Start(t)
+	require.NoError(t, w.Flush())
+	assert.Contains(t, server.Receive(t), `"short_message":"buffered"`)
+	assert.Equal(t, uint64(1), w.Stats().Sent)
+}
+
+func TestReconnectingWriterDialsInBackground(t *testing.T) {
+	server := testserver.NewTCP(t, testserver.NullTerminated)
+	dialing := make(chan struct{}, 1)
+	release := make(chan struct{})
+	first := true
+	w, err := gelf.NewReconnectingWriter(func() (net.Conn, error) {
+		if !first {
+			dialing <- struct{}{}
+			<-release
+		}
+		first = false
+		return net.Dial("tcp", server.Addr)
+	}, gelf.ReconnectConfig{MinBackoff: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer w.Close()
+
+	server.Stop()
+	assert.Eventually(t, func() bool { return !w.Stats().Connected }, 5*time.Second, 10*time.Millisecond)
+	assert.Error(t, w.WriteMessage(message("one")))
+
+	// Writing doesn't wait while the writer reconnects
+	<-dialing
+	assert.Error(t, w.WriteMessage(message("two")))
+	assert.Equal(t, 2, w.Stats().Buffered)
+
+	server.Start(t)
+	close(release)
+	assert.Contains(t, server.Receive(t), `"short_message":"one"`)
+	assert.Contains(t, server.Receive(t), `"short_message":"two"`)
+}
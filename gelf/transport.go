@@ -0,0 +1,329 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yext/glog-contrib/internal/tlsconfig"
+)
+
+// DefaultPort is the port used if the server URI does not specify one.
+const DefaultPort = "12201"
+
+// DefaultChunkSize is the default maximum size of UDP datagrams, which
+// avoids fragmentation on most networks.
+const DefaultChunkSize = 1420
+
+// Limits of chunked UDP messages.
+const (
+	maxChunks         = 128
+	chunkHeaderLength = 12
+)
+
+// The magic bytes starting each chunk of a chunked UDP message.
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// Compression is the compression of UDP messages.
+type Compression int
+
+// Compressions of UDP messages. Messages sent over TCP are not compressed.
+const (
+	CompressGzip Compression = iota
+	CompressZlib
+	CompressNone
+)
+
+// ParseCompression parses the name of a compression: "gzip", "zlib" or
+// "none".
+func ParseCompression(name string) (Compression, error) {
+	switch name {
+	case "gzip":
+		return CompressGzip, nil
+	case "zlib":
+		return CompressZlib, nil
+	case "none":
+		return CompressNone, nil
+	}
+	return 0, fmt.Errorf("unsupported compression %q", name)
+}
+
+// compress returns data compressed with the given compression.
+func compress(data []byte, c Compression) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch c {
+	case CompressNone:
+		return data, nil
+	case CompressGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported compression %d", c)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chunk splits data into chunks of at most size bytes, including the
+// chunk headers, with the given message ID. Data which fits in a single
+// chunk is returned unchanged.
+func chunk(data []byte, size int, id [8]byte) ([][]byte, error) {
+	if len(data) <= size {
+		return [][]byte{data}, nil
+	}
+	if size <= chunkHeaderLength {
+		return nil, fmt.Errorf("chunk size %d is too small", size)
+	}
+	n := size - chunkHeaderLength
+	count := (len(data) + n - 1) / n
+	if count > maxChunks {
+		return nil, fmt.Errorf("message of %d bytes requires %d chunks, more than the limit of %d",
+			len(data), count, maxChunks)
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * n
+		if end > len(data) {
+			end = len(data)
+		}
+		c := make([]byte, 0, chunkHeaderLength+end-i*n)
+		c = append(c, chunkMagic...)
+		c = append(c, id[:]...)
+		c = append(c, byte(i), byte(count))
+		c = append(c, data[i*n:end]...)
+		chunks = append(chunks, c)
+	}
+	return chunks, nil
+}
+
+// newMessageID returns a random ID for a chunked message.
+func newMessageID() ([8]byte, error) {
+	var id [8]byte
+	_, err := rand.Read(id[:])
+	return id, err
+}
+
+// Writer sends GELF messages to a server.
+type Writer interface {
+	WriteMessage(m *Message) error
+	Close() error
+}
+
+// Dial connects to the GELF server at the URI. The scheme selects the
+// transport:
+//
+//	udp://graylog:12201   compressed UDP datagrams, chunked if necessary
+//	tcp://graylog:12201   null byte terminated messages over TCP, reconnecting if it fails
+//	tls://graylog:12201   null byte terminated messages over TLS, reconnecting if it fails
+//	https://graylog/gelf  requests to a GELF HTTP input, or http://
+//
+// The port defaults to 12201 for UDP, TCP and TLS. Options are set with
+// query parameters:
+//
+//	compress              compression of UDP messages and HTTP requests: "gzip"
+//	                      (the default for UDP), "zlib" or "none" (the default for HTTP)
+//	ca                    file of PEM encoded certificates to verify the server with
+//	cert, key             files of the PEM encoded client certificate and key
+//	server_name           name used to verify the server, and sent with SNI
+//	insecure_skip_verify  disables verification of the server, if "true"
+//	batch_size            number of messages sent in each HTTP request
+//	flush_interval        longest time HTTP messages wait to be sent, while the batch is not full
+//	retries               number of times failed HTTP requests are retried
+//	timeout               timeout of connections, writes and HTTP requests
+//	buffer_size           number of messages buffered while reconnecting TCP and TLS,
+//	                      or waiting to be sent over HTTP
+//	max_backoff           longest time waited between attempts to reconnect
+func Dial(uri string) (Writer, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	timeout := 10 * time.Second
+	if v := query.Get("timeout"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("timeout: %v", err)
+		}
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+
+	switch u.Scheme {
+	case "udp":
+		compression := CompressGzip
+		if name := query.Get("compress"); name != "" {
+			if compression, err = ParseCompression(name); err != nil {
+				return nil, err
+			}
+		}
+		conn, err := dialer.Dial("udp", host)
+		if err != nil {
+			return nil, err
+		}
+		return &UDPWriter{Conn: conn, Compression: compression, ChunkSize: DefaultChunkSize}, nil
+	case "tcp":
+		return dialStream(query, timeout, func() (net.Conn, error) {
+			return dialer.Dial("tcp", host)
+		})
+	case "tls":
+		config, err := tlsconfig.FromQuery(query, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		return dialStream(query, timeout, func() (net.Conn, error) {
+			return tls.DialWithDialer(dialer, "tcp", host, config)
+		})
+	case "http", "https":
+		return dialHTTP(u, timeout)
+	}
+	return nil, fmt.Errorf("unsupported scheme %q: must be udp, tcp, tls, http or https", u.Scheme)
+}
+
+// dialStream returns a writer which reconnects with dial if the connection
+// fails.
+func dialStream(query url.Values, timeout time.Duration, dial func() (net.Conn, error)) (Writer, error) {
+	config := ReconnectConfig{WriteTimeout: timeout}
+	var err error
+	if v := query.Get("buffer_size"); v != "" {
+		if config.BufferSize, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("buffer_size: %v", err)
+		}
+	}
+	if v := query.Get("max_backoff"); v != "" {
+		if config.MaxBackoff, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("max_backoff: %v", err)
+		}
+	}
+	return NewReconnectingWriter(dial, config)
+}
+
+// dialHTTP returns a writer for the GELF HTTP input at the URI. No
+// connection is made until messages are sent.
+func dialHTTP(u *url.URL, timeout time.Duration) (Writer, error) {
+	query := u.Query()
+	var (
+		config = HTTPConfig{Retries: 3, Compression: CompressNone}
+		err    error
+	)
+	if name := query.Get("compress"); name != "" {
+		if config.Compression, err = ParseCompression(name); err != nil {
+			return nil, err
+		}
+	}
+	if v := query.Get("batch_size"); v != "" {
+		if config.BatchSize, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("batch_size: %v", err)
+		}
+	}
+	if v := query.Get("flush_interval"); v != "" {
+		if config.FlushInterval, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("flush_interval: %v", err)
+		}
+	}
+	if v := query.Get("retries"); v != "" {
+		if config.Retries, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("retries: %v", err)
+		}
+	}
+	if v := query.Get("buffer_size"); v != "" {
+		if config.BufferSize, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("buffer_size: %v", err)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if u.Scheme == "https" {
+		if transport.TLSClientConfig, err = tlsconfig.FromQuery(query, u.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	endpoint := *u
+	endpoint.RawQuery = ""
+	if endpoint.Path == "" {
+		endpoint.Path = "/gelf"
+	}
+	client := &http.Client{Transport: transport, Timeout: timeout}
+	return NewHTTPWriter(endpoint.String(), client, config), nil
+}
+
+// UDPWriter sends compressed messages as UDP datagrams, split into chunks
+// if they are larger than the ChunkSize.
+type UDPWriter struct {
+	Conn        net.Conn
+	Compression Compression
+	ChunkSize   int
+}
+
+// WriteMessage sends the message.
+func (w *UDPWriter) WriteMessage(m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if data, err = compress(data, w.Compression); err != nil {
+		return err
+	}
+	id, err := newMessageID()
+	if err != nil {
+		return err
+	}
+	chunks, err := chunk(data, w.ChunkSize, id)
+	if err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if _, err := w.Conn.Write(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (w *UDPWriter) Close() error {
+	return w.Conn.Close()
+}
+
+// TCPWriter sends uncompressed messages over a TCP connection, each
+// terminated by a null byte.
+type TCPWriter struct {
+	Conn net.Conn
+}
+
+// WriteMessage sends the message.
+func (w *TCPWriter) WriteMessage(m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Conn.Write(append(data, 0))
+	return err
+}
+
+// Close closes the connection.
+func (w *TCPWriter) Close() error {
+	return w.Conn.Close()
+}
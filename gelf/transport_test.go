@@ -0,0 +1,179 @@
+package gelf_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/gelf"
+)
+
+// udpServer receives GELF messages over UDP, reassembling chunked messages.
+type udpServer struct {
+	conn net.PacketConn
+}
+
+func newUDPServer(t *testing.T) *udpServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &udpServer{conn: conn}
+}
+
+func (s *udpServer) uri(query string) string {
+	return "udp://" + s.conn.LocalAddr().String() + query
+}
+
+// receive returns the next message and the number of chunks it was sent in.
+func (s *udpServer) receive(t *testing.T) ([]byte, int) {
+	var (
+		chunks [][]byte
+		id     []byte
+	)
+	buf := make([]byte, 65536)
+	for {
+		require.NoError(t, s.conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := s.conn.ReadFrom(buf)
+		require.NoError(t, err)
+		p := append([]byte(nil), buf[:n]...)
+		if !bytes.HasPrefix(p, []byte{0x1e, 0x0f}) {
+			return p, 1
+		}
+		if id == nil {
+			id = p[2:10]
+			chunks = make([][]byte, p[11])
+		}
+		assert.Equal(t, id, p[2:10])
+		chunks[p[10]] = p[12:]
+
+		complete := true
+		for _, c := range chunks {
+			complete = complete && c != nil
+		}
+		if complete {
+			return bytes.Join(chunks, nil), len(chunks)
+		}
+	}
+}
+
+func decompress(t *testing.T, data []byte) map[string]interface{} {
+	var r io.Reader = bytes.NewReader(data)
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(r)
+		require.NoError(t, err)
+		r = zr
+	case data[0] == 0x78:
+		zr, err := zlib.NewReader(r)
+		require.NoError(t, err)
+		r = zr
+	}
+	var m map[string]interface{}
+	require.NoError(t, json.NewDecoder(r).Decode(&m))
+	return m
+}
+
+func TestUDPWriter(t *testing.T) {
+	server := newUDPServer(t)
+	for _, query := range []string{"", "?compress=gzip", "?compress=zlib", "?compress=none"} {
+		w, err := gelf.Dial(server.uri(query))
+		require.NoError(t, err)
+		require.NoError(t, w.WriteMessage(&gelf.Message{Host: "web-1", ShortMessage: "hello", Level: gelf.LevelInfo}))
+		w.Close()
+
+		data, n := server.receive(t)
+		assert.Equal(t, 1, n)
+		m := decompress(t, data)
+		assert.Equal(t, "hello", m["short_message"], query)
+		assert.Equal(t, "1.1", m["version"], query)
+	}
+
+	_, err := gelf.Dial(server.uri("?compress=lz4"))
+	assert.Error(t, err)
+	_, err = gelf.Dial("ftp://localhost")
+	assert.Error(t, err)
+}
+
+func TestUDPWriterChunks(t *testing.T) {
+	server := newUDPServer(t)
+	w, err := gelf.Dial(server.uri("?compress=none"))
+	require.NoError(t, err)
+	defer w.Close()
+
+	long := strings.Repeat("x", 10000)
+	require.NoError(t, w.WriteMessage(&gelf.Message{Host: "web-1", ShortMessage: "long", FullMessage: long}))
+	data, n := server.receive(t)
+	assert.Equal(t, 8, n)
+	assert.Equal(t, long, decompress(t, data)["full_message"])
+
+	// Messages requiring more than 128 chunks are rejected
+	err = w.WriteMessage(&gelf.Message{Host: "web-1", ShortMessage: "long", FullMessage: strings.Repeat("x", 200000)})
+	assert.Error(t, err)
+}
+
+func TestTCPWriter(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	received := make(chan []string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+		var messages []string
+		r := bufio.NewReader(conn)
+		for {
+			m, err := r.ReadString(0)
+			if err != nil {
+				break
+			}
+			messages = append(messages, m)
+		}
+		received <- messages
+	}()
+
+	w, err := gelf.Dial("tcp://" + l.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, w.WriteMessage(&gelf.Message{Host: "web-1", ShortMessage: "one"}))
+	require.NoError(t, w.WriteMessage(&gelf.Message{Host: "web-1", ShortMessage: "two"}))
+	w.Close()
+
+	messages := <-received
+	if assert.Len(t, messages, 2) {
+		assert.True(t, strings.HasSuffix(messages[0], "\x00"))
+		assert.Contains(t, messages[0], `"short_message":"one"`)
+		assert.Contains(t, messages[1], `"short_message":"two"`)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	server := newUDPServer(t)
+	events := make(chan glog.Event, 1)
+	events <- glog.Event{Severity: "ERROR", Message: []byte("failed"),
+		Data: []interface{}{map[string]interface{}{"user id": "alice", "id": 7}}}
+	close(events)
+
+	require.NoError(t, gelf.Capture(map[string]interface{}{"service": "web"}, server.uri(""), 10, events))
+	data, _ := server.receive(t)
+	m := decompress(t, data)
+	assert.Equal(t, "failed", m["short_message"])
+	assert.Equal(t, float64(gelf.LevelError), m["level"])
+	assert.Equal(t, "web", m["_service"])
+	assert.Equal(t, "alice", m["_user_id"])
+	assert.Equal(t, float64(7), m["_id_"])
+	assert.Equal(t, "ERROR", m["_levelName"])
+}
@@ -1,18 +1,27 @@
 module github.com/yext/glog-contrib
 
-go 1.16
+go 1.18
 
 require (
-	github.com/aphistic/golf v0.0.0-20180712155816-02c07f170c5a
-	github.com/aphistic/sweet v0.3.0 // indirect
-	github.com/getsentry/sentry-go v0.11.0
-	github.com/google/uuid v1.2.0 // indirect
+	github.com/getsentry/sentry-go v0.28.1
 	github.com/kr/pretty v0.3.0
-	github.com/kylelemons/godebug v1.1.0 // indirect
-	github.com/stretchr/testify v1.7.0
-	github.com/theothertomelliott/go-must v0.0.0-20180901182306-492b25fad7e5 // indirect
+	github.com/stretchr/testify v1.8.2
 	github.com/yext/glog v0.0.0-20210527194735-eb643387a7e2
 	github.com/yext/yerrors v0.0.0-20201026182705-b30cf71caa54
-	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+	golang.org/x/time v0.3.0
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.6.1 // indirect
+	github.com/theothertomelliott/go-must v0.0.0-20180901182306-492b25fad7e5 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
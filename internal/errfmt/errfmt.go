@@ -0,0 +1,51 @@
+// Package errfmt formats errors and stack traces as text fields, for the
+// gelf and jsonlog packages.
+package errfmt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Chain returns the errors in the chain of err, from the outermost to the
+// root cause, following Unwrap and Cause, up to maxDepth errors.
+func Chain(err error, maxDepth int) []error {
+	var chain []error
+	for i := 0; i < maxDepth && err != nil; i++ {
+		chain = append(chain, err)
+		switch previous := err.(type) {
+		case interface{ Unwrap() error }:
+			err = previous.Unwrap()
+		case interface{ Cause() error }:
+			err = previous.Cause()
+		default:
+			err = nil
+		}
+	}
+	return chain
+}
+
+// Stack formats a stack trace like a Go panic, from the innermost frame to
+// the outermost.
+func Stack(st *sentry.Stacktrace) string {
+	var b strings.Builder
+	for i := len(st.Frames) - 1; i >= 0; i-- {
+		f := st.Frames[i]
+		file := f.AbsPath
+		if file == "" {
+			file = f.Filename
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", Function(f), file, f.Lineno)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Function returns the function of the frame, qualified by its package.
+func Function(f sentry.Frame) string {
+	if f.Module == "" {
+		return f.Function
+	}
+	return f.Module + "." + f.Function
+}
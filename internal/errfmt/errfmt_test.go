@@ -0,0 +1,32 @@
+package errfmt_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog-contrib/internal/errfmt"
+)
+
+type causer struct{ cause error }
+
+func (c causer) Error() string { return "caused: " + c.cause.Error() }
+func (c causer) Cause() error  { return c.cause }
+
+func TestChain(t *testing.T) {
+	root := errors.New("root")
+	err := fmt.Errorf("wrapped: %w", causer{root})
+	assert.Equal(t, []error{err, causer{root}, root}, errfmt.Chain(err, 10))
+	assert.Len(t, errfmt.Chain(err, 2), 2)
+	assert.Empty(t, errfmt.Chain(nil, 10))
+}
+
+func TestStack(t *testing.T) {
+	st := &sentry.Stacktrace{Frames: []sentry.Frame{
+		{Module: "main", Function: "main", AbsPath: "/src/main.go", Lineno: 10},
+		{Function: "helper", Filename: "helper.go", Lineno: 20},
+	}}
+	assert.Equal(t, "helper\n\thelper.go:20\nmain.main\n\t/src/main.go:10", errfmt.Stack(st))
+}
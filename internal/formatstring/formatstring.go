@@ -0,0 +1,20 @@
+// Package formatstring groups messages by the format string they were
+// logged with, for the sentry and gelf packages.
+package formatstring
+
+import (
+	"regexp"
+	"strings"
+)
+
+var verbRe = regexp.MustCompile(`%#?\+?\w+ ?`)
+
+// Cleanup takes in a message with printf formatter characters
+// (e.g. "error performing action %s: %s") and strips the percent characters,
+// also cleaning up whitespace and trailing colons.
+func Cleanup(format string) string {
+	format = verbRe.ReplaceAllString(format, "")
+	format = strings.TrimSpace(format)
+	format = strings.TrimSuffix(format, ":")
+	return strings.TrimSpace(format)
+}
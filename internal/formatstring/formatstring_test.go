@@ -0,0 +1,20 @@
+package formatstring_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog-contrib/internal/formatstring"
+)
+
+func TestCleanup(t *testing.T) {
+	for format, expected := range map[string]string{
+		"error performing action %s: %s": "error performing action",
+		"unable to start: %v":            "unable to start",
+		"%d of %+v failed":               "of failed",
+		"request %#v %q failed":          "request failed",
+		"no verbs":                       "no verbs",
+	} {
+		assert.Equal(t, expected, formatstring.Cleanup(format), format)
+	}
+}
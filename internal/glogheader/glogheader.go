@@ -0,0 +1,48 @@
+// Package glogheader parses the header glog adds to each message, for the
+// backends which send messages to other logging systems.
+package glogheader
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Header is the header glog adds to each message:
+//
+//	Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg...
+//
+// The thread ID is not written by all versions of glog, in which case it is
+// zero.
+type Header struct {
+	Time     time.Time
+	ThreadID int
+	File     string
+	Line     int
+}
+
+var headerRe = regexp.MustCompile(
+	`^[IWEF](\d\d)(\d\d) (\d\d):(\d\d):(\d\d)\.(\d{6}) +(?:(\d+) )?([^\s:\]]+):(\d+)\] ?`)
+
+// Parse parses the glog header of a message, returning the header and the
+// rest of the message. The header only contains the month and day, so the
+// year is assumed to be the one in which the message was logged most
+// recently before now.
+func Parse(msg string, now time.Time) (Header, string, bool) {
+	m := headerRe.FindStringSubmatch(msg)
+	if m == nil {
+		return Header{}, msg, false
+	}
+	n := make([]int, len(m))
+	for i := 1; i < len(m); i++ {
+		n[i], _ = strconv.Atoi(m[i])
+	}
+
+	h := Header{ThreadID: n[7], File: m[8], Line: n[9]}
+	h.Time = time.Date(now.Year(), time.Month(n[1]), n[2], n[3], n[4], n[5], n[6]*1000, now.Location())
+	// Messages logged on December 31st may be sent on January 1st.
+	if h.Time.After(now.Add(24 * time.Hour)) {
+		h.Time = h.Time.AddDate(-1, 0, 0)
+	}
+	return h, msg[len(m[0]):], true
+}
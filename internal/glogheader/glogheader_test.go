@@ -0,0 +1,38 @@
+package glogheader_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog-contrib/internal/glogheader"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	h, msg, ok := glogheader.Parse("E0102 15:04:05.678901 1234 server.go:42] request failed\n", now)
+	assert.True(t, ok)
+	assert.Equal(t, "request failed\n", msg)
+	assert.Equal(t, glogheader.Header{
+		Time:     time.Date(2024, 1, 2, 15, 4, 5, 678901000, time.UTC),
+		ThreadID: 1234,
+		File:     "server.go",
+		Line:     42,
+	}, h)
+
+	// The thread ID is optional
+	h, msg, ok = glogheader.Parse("I0102 15:04:05.678901 server.go:7] started", now)
+	assert.True(t, ok)
+	assert.Equal(t, "started", msg)
+	assert.Zero(t, h.ThreadID)
+	assert.Equal(t, 7, h.Line)
+
+	// Messages logged on December 31st are from the previous year
+	h, _, _ = glogheader.Parse("I1231 23:59:59.000000 server.go:7] started", now)
+	assert.Equal(t, 2023, h.Time.Year())
+
+	_, msg, ok = glogheader.Parse("no header", now)
+	assert.False(t, ok)
+	assert.Equal(t, "no header", msg)
+}
@@ -0,0 +1,373 @@
+// Package reconnect sends messages over connections which reconnect if they
+// fail, for the backends which send messages to servers.
+package reconnect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config configures how a Writer buffers messages and reconnects.
+type Config struct {
+	// BufferSize is the maximum number of messages buffered while
+	// disconnected. When it is full, the oldest messages are dropped.
+	// Defaults to 1000.
+	BufferSize int
+	// MinBackoff is the time waited before the first attempt to reconnect,
+	// doubled after each failed attempt. Defaults to 100ms.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest time waited between attempts to reconnect.
+	// Defaults to 30s, and is at least MinBackoff.
+	MaxBackoff time.Duration
+	// WriteTimeout is the longest time a write can block before the
+	// connection is considered dead. Defaults to 10s.
+	WriteTimeout time.Duration
+	// Watch is whether to detect when the server closes the connection, by
+	// reading from it. It must only be set for streams, such as TCP and
+	// TLS, to servers which never send data.
+	Watch bool
+}
+
+// Stats are statistics of a Writer.
+type Stats struct {
+	// Connected is whether the writer is currently connected.
+	Connected bool
+	// Sent is the number of messages written to the connection.
+	Sent uint64
+	// Buffered is the number of messages waiting to be sent.
+	Buffered int
+	// Dropped is the number of messages dropped because the buffer was full.
+	Dropped uint64
+	// Failed is the number of messages dropped because writing them failed
+	// for a reason other than the connection, such as their size.
+	Failed uint64
+	// Reconnects is the number of times the writer has reconnected.
+	Reconnects uint64
+}
+
+// Writer sends messages over a connection. If the connection fails or is
+// closed by the server, messages are buffered while it reconnects in the
+// background, with exponential backoff and jitter.
+type Writer struct {
+	dial   func() (net.Conn, error)
+	config Config
+
+	mu         sync.Mutex
+	conn       net.Conn
+	pending    [][]byte
+	backoff    time.Duration
+	nextDial   time.Time
+	sent       uint64
+	dropped    uint64
+	failed     uint64
+	reconnects uint64
+	closed     bool
+
+	// flushes requests the retry loop to reconnect immediately, and send
+	// the buffered messages.
+	flushes  chan chan error
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// New connects with dial, returning an error if the first connection
+// fails. Buffered messages are retried in the background until the writer
+// is closed.
+func New(dial func() (net.Conn, error), config Config) (*Writer, error) {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		dial:    dial,
+		config:  config,
+		flushes: make(chan chan error),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.connected(conn)
+	go w.retryLoop()
+	return w, nil
+}
+
+// connected starts using a new connection. The lock must be held, unless
+// the writer is being constructed.
+func (w *Writer) connected(conn net.Conn) {
+	w.conn = conn
+	w.backoff = 0
+	if w.config.Watch {
+		go w.watch(conn)
+	}
+}
+
+// watch detects when the server closes the connection. The servers never
+// send data, so any result from reading means the connection has ended.
+func (w *Writer) watch(conn net.Conn) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			break
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == conn {
+		w.disconnected()
+	}
+}
+
+// disconnected closes the connection, and schedules the next attempt to
+// reconnect. The lock must be held.
+func (w *Writer) disconnected() {
+	w.conn.Close()
+	w.conn = nil
+	w.scheduleReconnect()
+}
+
+// scheduleReconnect increases the backoff, and sets the time of the next
+// attempt to reconnect. The lock must be held.
+func (w *Writer) scheduleReconnect() {
+	if w.backoff == 0 {
+		w.backoff = w.config.MinBackoff
+	} else if w.backoff *= 2; w.backoff > w.config.MaxBackoff {
+		w.backoff = w.config.MaxBackoff
+	}
+	// Wait between half and all of the backoff, so that many clients
+	// don't reconnect at the same time after a server restarts.
+	jitter := time.Duration(rand.Int63n(int64(w.backoff)/2 + 1))
+	w.nextDial = time.Now().Add(w.backoff/2 + jitter)
+}
+
+// retryLoop reconnects and sends the buffered messages. It is the only
+// place connections are dialed after the first, so that writing never waits
+// for a connection.
+func (w *Writer) retryLoop() {
+	defer close(w.done)
+	t := time.NewTicker(w.config.MinBackoff)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			// Errors are returned by the next call to Write.
+			_ = w.retry(false)
+		case reply := <-w.flushes:
+			reply <- w.retry(true)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// retry reconnects if there are buffered messages and the backoff has
+// passed, or regardless of the backoff if immediate is set, then sends the
+// buffered messages. The lock is not held while dialing.
+func (w *Writer) retry(immediate bool) error {
+	w.mu.Lock()
+	reconnect := w.conn == nil && len(w.pending) > 0 &&
+		(immediate || !time.Now().Before(w.nextDial))
+	w.mu.Unlock()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if reconnect {
+		conn, err = w.dial()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.scheduleReconnect()
+		return err
+	}
+	if conn != nil {
+		w.reconnects++
+		w.connected(conn)
+	}
+	return w.send()
+}
+
+// Write buffers the message, and sends the buffered messages if connected.
+// It returns an error if they can't be sent, in which case they remain
+// buffered until the writer reconnects, unless the connection was not the
+// cause, in which case the failed messages are dropped.
+func (w *Writer) Write(msg []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return fmt.Errorf("writer is closed")
+	}
+	if len(w.pending) >= w.config.BufferSize {
+		w.pending = w.pending[1:]
+		w.dropped++
+	}
+	w.pending = append(w.pending, msg)
+	return w.send()
+}
+
+// send writes the buffered messages to the connection. The lock must be
+// held.
+func (w *Writer) send() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	if w.conn == nil {
+		return fmt.Errorf("not connected, %d messages buffered", len(w.pending))
+	}
+	var failed error
+	for len(w.pending) > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.config.WriteTimeout))
+		if _, err := w.conn.Write(w.pending[0]); err != nil {
+			if connectionError(err) {
+				w.disconnected()
+				return err
+			}
+			// The message would fail again after reconnecting, such as a
+			// datagram which is too large, so it is dropped rather than
+			// blocking the others.
+			w.pending = w.pending[1:]
+			w.failed++
+			if failed == nil {
+				failed = err
+			}
+			continue
+		}
+		w.pending = w.pending[1:]
+		w.sent++
+	}
+	return failed
+}
+
+// connectionError returns whether a write failed because of the connection,
+// so that it can succeed after reconnecting.
+func connectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, target := range []error{
+		net.ErrClosed,
+		io.EOF,
+		io.ErrUnexpectedEOF,
+		os.ErrDeadlineExceeded,
+		syscall.ECONNREFUSED,
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.EPIPE,
+		syscall.ENOTCONN,
+		syscall.ENETUNREACH,
+		syscall.EHOSTUNREACH,
+		syscall.ETIMEDOUT,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush sends the buffered messages, reconnecting immediately if not
+// connected, regardless of the backoff.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		return fmt.Errorf("writer is closed")
+	}
+	return w.retryNow()
+}
+
+// retryNow asks the retry loop to reconnect immediately, and waits for the
+// buffered messages to be sent.
+func (w *Writer) retryNow() error {
+	reply := make(chan error, 1)
+	select {
+	case w.flushes <- reply:
+		return <-reply
+	case <-w.done:
+		return fmt.Errorf("writer is closed")
+	}
+}
+
+// Stats returns statistics of the writer.
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return Stats{
+		Connected:  w.conn != nil,
+		Sent:       w.sent,
+		Buffered:   len(w.pending),
+		Dropped:    w.dropped,
+		Failed:     w.failed,
+		Reconnects: w.reconnects,
+	}
+}
+
+// Delivered returns the number of messages sent, failed and still
+// buffered. Messages dropped, or not sent before the writer was closed, are
+// counted as failed.
+func (w *Writer) Delivered() (sent, failed uint64, buffered int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	failed = w.dropped + w.failed
+	if w.closed {
+		failed += uint64(len(w.pending))
+	} else {
+		buffered = len(w.pending)
+	}
+	return w.sent, failed, buffered
+}
+
+// Close attempts to send any buffered messages, and closes the connection.
+// It returns an error if any messages were not sent.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	// Make a final attempt to reconnect, regardless of the backoff.
+	err := w.retryNow()
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err != nil {
+		return fmt.Errorf("%d messages not sent: %v", len(w.pending), err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package testserver provides a TCP server receiving framed messages, for
+// testing the writers of the gelf and syslog packages.
+package testserver
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// maxMessageSize is the size of the largest message which can be received.
+const maxMessageSize = 1 << 20
+
+// NullTerminated splits null byte terminated messages, as sent by GELF
+// over TCP.
+func NullTerminated(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	return 0, nil, nil
+}
+
+// OctetCounted splits messages prefixed with their length and a space, as
+// sent by syslog over TCP (RFC 6587).
+func OctetCounted(data []byte, atEOF bool) (int, []byte, error) {
+	i := bytes.IndexByte(data, ' ')
+	if i < 0 {
+		return 0, nil, nil
+	}
+	n, err := strconv.Atoi(string(data[:i]))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < i+1+n {
+		return 0, nil, nil
+	}
+	return i + 1 + n, data[i+1 : i+1+n], nil
+}
+
+// TCP receives messages split by a bufio.SplitFunc, and can be stopped and
+// restarted on the same address.
+type TCP struct {
+	// Addr is the address the server listens on.
+	Addr string
+
+	split    bufio.SplitFunc
+	messages chan string
+
+	mu    sync.Mutex
+	l     net.Listener
+	conns []net.Conn
+}
+
+// NewTCP starts a server on a local port, which is stopped when the test
+// ends.
+func NewTCP(t *testing.T, split bufio.SplitFunc) *TCP {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &TCP{Addr: l.Addr().String(), split: split, messages: make(chan string, 100)}
+	s.serve(l)
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// Start restarts a stopped server on the same address.
+func (s *TCP) Start(t *testing.T) {
+	l, err := net.Listen("tcp", s.Addr)
+	require.NoError(t, err)
+	s.serve(l)
+}
+
+func (s *TCP) serve(l net.Listener) {
+	s.mu.Lock()
+	s.l = l
+	s.mu.Unlock()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				scanner.Buffer(nil, maxMessageSize)
+				scanner.Split(s.split)
+				for scanner.Scan() {
+					s.messages <- scanner.Text()
+				}
+			}()
+		}
+	}()
+}
+
+// Stop closes the listener and all connections.
+func (s *TCP) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.l.Close()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// Receive returns the next message, failing the test if none is received
+// within 5 seconds.
+func (s *TCP) Receive(t *testing.T) string {
+	select {
+	case m := <-s.messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
@@ -0,0 +1,55 @@
+// Package tlsconfig builds TLS configurations from the query parameters of
+// server URIs, for the backends which send messages over TLS.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// FromQuery builds the TLS configuration from the query parameters of a
+// server URI:
+//
+//	ca                    file of PEM encoded certificates to verify the server with
+//	cert, key             files of the PEM encoded client certificate and key
+//	server_name           name used to verify the server, and sent with SNI
+//	insecure_skip_verify  disables verification of the server, if "true"
+func FromQuery(query url.Values, host string) (*tls.Config, error) {
+	config := &tls.Config{ServerName: host}
+	if name := query.Get("server_name"); name != "" {
+		config.ServerName = name
+	}
+	if v := query.Get("insecure_skip_verify"); v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("insecure_skip_verify: %v", err)
+		}
+		config.InsecureSkipVerify = skip
+	}
+	if ca := query.Get("ca"); ca != "" {
+		b, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%s: no certificates found", ca)
+		}
+	}
+	cert, key := query.Get("cert"), query.Get("key")
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, fmt.Errorf("both cert and key must be specified")
+		}
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+	return config, nil
+}
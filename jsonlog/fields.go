@@ -0,0 +1,96 @@
+package jsonlog
+
+// FieldNames are the names of the fields written for each event. Fields
+// with an empty name are not written, except Data.
+type FieldNames struct {
+	// Time is the time the event was logged.
+	Time string
+	// Severity is the glog severity, such as "ERROR", or the name it is
+	// mapped to by Options.Levels.
+	Severity string
+	// Message is the message, without the glog header.
+	Message string
+	// Caller is the call site, formatted as "file:line".
+	Caller string
+	// File and Line are the file and line of the call site.
+	File string
+	Line string
+	// Function is the function of the call site, including its package.
+	Function string
+	// SourceLocation is an object with the file, line and function of the
+	// call site, as used by GCP Cloud Logging.
+	SourceLocation string
+	// Stack is the stack trace, formatted like a Go panic, which glog only
+	// records for ERROR and FATAL events. The stack trace of the error
+	// passed to glog is used if it has one.
+	Stack string
+	// ErrorMessage and ErrorType are the message and type of the error
+	// passed to glog.
+	ErrorMessage string
+	ErrorType    string
+	// ErrorChain is a list of the messages and types of each error in the
+	// chain, from the outermost to the root cause.
+	ErrorChain string
+	// Data is an object containing the data maps passed to glog. If empty,
+	// their keys are added to the event itself, unless they conflict with
+	// the other fields.
+	Data string
+}
+
+// DefaultFields are the field names used if none are set.
+var DefaultFields = FieldNames{
+	Time:         "time",
+	Severity:     "severity",
+	Message:      "message",
+	Caller:       "caller",
+	Function:     "function",
+	Stack:        "stack",
+	ErrorMessage: "error",
+	ErrorType:    "error_type",
+	ErrorChain:   "errors",
+}
+
+// ECSFields are the field names of the Elastic Common Schema.
+var ECSFields = FieldNames{
+	Time:         "@timestamp",
+	Severity:     "log.level",
+	Message:      "message",
+	File:         "log.origin.file.name",
+	Line:         "log.origin.file.line",
+	Function:     "log.origin.function",
+	Stack:        "error.stack_trace",
+	ErrorMessage: "error.message",
+	ErrorType:    "error.type",
+	ErrorChain:   "error.chain",
+}
+
+// GCPFields are the field names recognized by GCP Cloud Logging. Use them
+// with GCPLevels, as GCP has no FATAL severity.
+var GCPFields = FieldNames{
+	Time:           "time",
+	Severity:       "severity",
+	Message:        "message",
+	SourceLocation: "logging.googleapis.com/sourceLocation",
+	Stack:          "stack_trace",
+	ErrorMessage:   "error",
+	ErrorType:      "error_type",
+	ErrorChain:     "errors",
+}
+
+// GCPLevels maps glog severities to GCP Cloud Logging severities.
+var GCPLevels = map[string]string{
+	"FATAL": "CRITICAL",
+}
+
+// DatadogFields are the standard attributes of Datadog logs.
+var DatadogFields = FieldNames{
+	Time:         "timestamp",
+	Severity:     "status",
+	Message:      "message",
+	Caller:       "caller",
+	Function:     "logger.method_name",
+	Stack:        "error.stack",
+	ErrorMessage: "error.message",
+	ErrorType:    "error.kind",
+	ErrorChain:   "error.chain",
+}
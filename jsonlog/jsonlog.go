@@ -0,0 +1,209 @@
+// Package jsonlog writes glog events as JSON lines, such as to stdout for
+// collection by a Kubernetes node agent.
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/internal/errfmt"
+	"github.com/yext/glog-contrib/internal/glogheader"
+	errstack "github.com/yext/glog-contrib/stacktrace"
+)
+
+// The maximum number of wrapped errors written in the error chain.
+const maxErrorDepth = 10
+
+// Options configures how events are written by Capture.
+type Options struct {
+	// Fields are the names of the fields written. Defaults to
+	// DefaultFields.
+	Fields FieldNames
+	// Levels maps glog severities to the names written, such as GCPLevels.
+	// Severities which are not mapped are written unchanged.
+	Levels map[string]string
+	// TimeFormat is the layout of timestamps. Defaults to
+	// time.RFC3339Nano.
+	TimeFormat string
+}
+
+// Capture writes each event from the channel to w as a JSON object on a
+// single line, until the channel is closed. Events which can't be written
+// are skipped, so that glog is never blocked by a failing writer, and once
+// the channel is closed an error reports how many were not written.
+//
+// For example, to write events to stdout with the field names of GCP Cloud
+// Logging:
+//
+//	go jsonlog.Capture(os.Stdout,
+//		jsonlog.Options{Fields: jsonlog.GCPFields, Levels: jsonlog.GCPLevels},
+//		glog.RegisterBackend())
+func Capture(w io.Writer, opts Options, eventCh <-chan glog.Event) error {
+	if opts.Fields == (FieldNames{}) {
+		opts.Fields = DefaultFields
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339Nano
+	}
+
+	var (
+		buf      bytes.Buffer
+		failed   int
+		firstErr error
+	)
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for e := range eventCh {
+		buf.Reset()
+		// Each line is written with a single call, so that lines written
+		// concurrently are not interleaved.
+		err := enc.Encode(buildRecord(e, opts, time.Now()))
+		if err == nil {
+			_, err = w.Write(buf.Bytes())
+		}
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("%d events not written: %v", failed, firstErr)
+	}
+	return nil
+}
+
+// buildRecord converts a glog event into the object written for it.
+func buildRecord(e glog.Event, opts Options, now time.Time) map[string]interface{} {
+	f := opts.Fields
+	record := map[string]interface{}{}
+	data := record
+	if f.Data != "" {
+		data = map[string]interface{}{}
+	}
+	var err error
+	for _, d := range e.Data {
+		switch t := d.(type) {
+		case map[string]interface{}:
+			for k, v := range t {
+				data[k] = value(v)
+			}
+		case glog.ErrorArg:
+			if err == nil {
+				err = t.Error
+			}
+		}
+	}
+	if f.Data != "" && len(data) > 0 {
+		record[f.Data] = data
+	}
+
+	// The fields below take precedence over the data maps.
+	set := func(name string, v interface{}) {
+		if name != "" {
+			record[name] = v
+		}
+	}
+
+	timestamp := now
+	header, message, ok := glogheader.Parse(string(e.Message), now)
+	if ok {
+		timestamp = header.Time
+	}
+	set(f.Time, timestamp.Format(opts.TimeFormat))
+	severity := e.Severity
+	if name, ok := opts.Levels[severity]; ok {
+		severity = name
+	}
+	set(f.Severity, severity)
+	set(f.Message, strings.TrimRight(message, "\r\n"))
+
+	// The call site is the innermost frame of the stack trace, which
+	// includes the path of the file and the function, if there is one.
+	file, line, function := header.File, header.Line, ""
+	if len(e.StackTrace) > 0 {
+		st := errstack.ExtractFrames(e.StackTrace, nil)
+		if n := len(st.Frames); n > 0 {
+			// Frames are ordered from outermost to innermost.
+			frame := st.Frames[n-1]
+			file, line, function = frame.Filename, frame.Lineno, errfmt.Function(frame)
+		}
+	}
+	if file != "" {
+		set(f.Caller, fmt.Sprintf("%s:%d", file, line))
+		set(f.File, file)
+		set(f.Line, line)
+		set(f.SourceLocation, map[string]interface{}{
+			"file": file,
+			// The line is a string, as it is an int64 in the LogEntry API.
+			"line":     strconv.Itoa(line),
+			"function": function,
+		})
+	}
+	if function != "" {
+		set(f.Function, function)
+	}
+
+	var st *sentry.Stacktrace
+	if err != nil {
+		st = errstack.ExtractStacktrace(err)
+	}
+	if (st == nil || len(st.Frames) == 0) && len(e.StackTrace) > 0 {
+		st = errstack.ExtractFrames(e.StackTrace, err)
+	}
+	if st != nil && len(st.Frames) > 0 {
+		set(f.Stack, errfmt.Stack(st))
+	}
+
+	if err != nil {
+		set(f.ErrorMessage, err.Error())
+		set(f.ErrorType, fmt.Sprintf("%T", err))
+		set(f.ErrorChain, errorChain(err))
+	}
+	return record
+}
+
+// errorChain returns the message and type of each error in the chain, from
+// the outermost to the root cause.
+func errorChain(err error) []map[string]string {
+	var chain []map[string]string
+	for _, err := range errfmt.Chain(err, maxErrorDepth) {
+		chain = append(chain, map[string]string{
+			"message": err.Error(),
+			"type":    fmt.Sprintf("%T", err),
+		})
+	}
+	return chain
+}
+
+// value returns a value of a data map which can be encoded as JSON. Errors
+// are written as their message, and values which can't be encoded, such as
+// NaN or infinite floats, are formatted with fmt.
+func value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, string, bool, int, int64:
+		return v
+	case float32:
+		return value(float64(t))
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return fmt.Sprint(t)
+		}
+		return t
+	case error:
+		return t.Error()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return v
+}
@@ -0,0 +1,195 @@
+package jsonlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/jsonlog"
+	"golang.org/x/xerrors"
+)
+
+// capture writes the events with Capture, and returns the decoded lines.
+func capture(t *testing.T, opts jsonlog.Options, events ...glog.Event) []map[string]interface{} {
+	ch := make(chan glog.Event, len(events))
+	for _, e := range events {
+		ch <- e
+	}
+	close(ch)
+
+	var buf bytes.Buffer
+	require.NoError(t, jsonlog.Capture(&buf, opts, ch))
+	var records []map[string]interface{}
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		require.True(t, strings.HasSuffix(line, "\n"), line)
+		var r map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &r), line)
+		records = append(records, r)
+	}
+	require.Len(t, records, len(events))
+	return records
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 20)
+	return pcs[:runtime.Callers(2, pcs)]
+}
+
+func openConfig() error {
+	_, err := os.Open("/does/not/exist")
+	return xerrors.Errorf("opening config: %w", err)
+}
+
+func TestCapture(t *testing.T) {
+	year := time.Now().Year()
+	records := capture(t, jsonlog.Options{},
+		glog.Event{
+			Severity: "INFO",
+			Message:  []byte("I0102 15:04:05.123456 server.go:42] listening\n"),
+			Data: []interface{}{
+				map[string]interface{}{"port": 8080, "message": "ignored"},
+				map[string]interface{}{"user": "alice", "ch": make(chan int), "cause": errors.New("denied")},
+			},
+		},
+		glog.Event{Severity: "WARNING", Message: []byte("no header")},
+	)
+
+	r := records[0]
+	assert.Equal(t, time.Date(year, 1, 2, 15, 4, 5, 123456000, time.Local).Format(time.RFC3339Nano), r["time"])
+	assert.Equal(t, "INFO", r["severity"])
+	assert.Equal(t, "listening", r["message"])
+	assert.Equal(t, "server.go:42", r["caller"])
+	assert.NotContains(t, r, "stack")
+	assert.NotContains(t, r, "error")
+
+	// Data maps are merged, but don't replace the other fields
+	assert.Equal(t, float64(8080), r["port"])
+	assert.Equal(t, "alice", r["user"])
+	assert.Equal(t, "denied", r["cause"])
+	assert.Contains(t, r["ch"], "0x")
+
+	r = records[1]
+	assert.Equal(t, "no header", r["message"])
+	assert.NotContains(t, r, "caller")
+	_, err := time.Parse(time.RFC3339Nano, r["time"].(string))
+	assert.NoError(t, err)
+}
+
+func TestCaptureNonFiniteFloats(t *testing.T) {
+	r := capture(t, jsonlog.Options{}, glog.Event{
+		Severity: "INFO",
+		Message:  []byte("ratio"),
+		Data: []interface{}{map[string]interface{}{
+			"nan":     math.NaN(),
+			"inf":     math.Inf(1),
+			"neg_inf": float32(math.Inf(-1)),
+			"ratio":   0.5,
+		}},
+	})[0]
+
+	// Floats which JSON can't represent are written as strings, rather than
+	// dropping the line
+	assert.Equal(t, "NaN", r["nan"])
+	assert.Equal(t, "+Inf", r["inf"])
+	assert.Equal(t, "-Inf", r["neg_inf"])
+	assert.Equal(t, 0.5, r["ratio"])
+}
+
+func TestCaptureError(t *testing.T) {
+	e := glog.Event{
+		Severity:   "ERROR",
+		Message:    []byte("E0102 15:04:05.123456 main.go:10] loading failed"),
+		StackTrace: callers(),
+		Data:       []interface{}{glog.ErrorArg{Error: openConfig()}},
+	}
+	r := capture(t, jsonlog.Options{}, e)[0]
+
+	assert.Equal(t, "opening config: open /does/not/exist: no such file or directory", r["error"])
+	assert.Equal(t, "*xerrors.wrapError", r["error_type"])
+	if chain, ok := r["errors"].([]interface{}); assert.True(t, ok) && assert.Len(t, chain, 3) {
+		assert.Equal(t, map[string]interface{}{"message": "no such file or directory", "type": "syscall.Errno"}, chain[2])
+	}
+	// The stack trace is the error's, and the call site is where it was
+	// logged
+	assert.True(t, strings.HasPrefix(r["stack"].(string), "github.com/yext/glog-contrib/jsonlog_test.openConfig\n\t"), r["stack"])
+	assert.Equal(t, "github.com/yext/glog-contrib/jsonlog_test.TestCaptureError", r["function"])
+	assert.Contains(t, r["caller"], "jsonlog/jsonlog_test.go:")
+}
+
+func TestCaptureFieldNames(t *testing.T) {
+	e := glog.Event{
+		Severity:   "FATAL",
+		Message:    []byte("F0102 15:04:05.123456 main.go:10] crashed"),
+		StackTrace: callers(),
+		Data:       []interface{}{glog.ErrorArg{Error: errors.New("out of memory")}, map[string]interface{}{"user": "alice"}},
+	}
+
+	r := capture(t, jsonlog.Options{Fields: jsonlog.GCPFields, Levels: jsonlog.GCPLevels}, e)[0]
+	assert.Equal(t, "CRITICAL", r["severity"])
+	if loc, ok := r["logging.googleapis.com/sourceLocation"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Contains(t, loc["file"], "jsonlog/jsonlog_test.go")
+		assert.IsType(t, "", loc["line"])
+		assert.Equal(t, "github.com/yext/glog-contrib/jsonlog_test.TestCaptureFieldNames", loc["function"])
+	}
+	assert.Contains(t, r, "stack_trace")
+
+	r = capture(t, jsonlog.Options{Fields: jsonlog.ECSFields, TimeFormat: time.RFC3339}, e)[0]
+	assert.Equal(t, "FATAL", r["log.level"])
+	assert.Equal(t, time.Date(time.Now().Year(), 1, 2, 15, 4, 5, 0, time.Local).Format(time.RFC3339), r["@timestamp"])
+	assert.Contains(t, r["log.origin.file.name"], "jsonlog/jsonlog_test.go")
+	assert.NotZero(t, r["log.origin.file.line"])
+	assert.Equal(t, "out of memory", r["error.message"])
+	assert.Equal(t, "*errors.errorString", r["error.type"])
+
+	fields := jsonlog.DatadogFields
+	fields.Data = "attributes"
+	r = capture(t, jsonlog.Options{Fields: fields}, e)[0]
+	assert.Equal(t, "FATAL", r["status"])
+	assert.Equal(t, "*errors.errorString", r["error.kind"])
+	assert.Contains(t, r, "error.stack")
+	assert.Equal(t, map[string]interface{}{"user": "alice"}, r["attributes"])
+	assert.NotContains(t, r, "user")
+}
+
+// failingWriter fails to write the first n lines.
+type failingWriter struct {
+	n     int
+	lines []string
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n > 0 {
+		w.n--
+		return 0, errors.New("broken pipe")
+	}
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestCaptureWriteErrors(t *testing.T) {
+	ch := make(chan glog.Event, 3)
+	for _, m := range []string{"one", "two", "three"} {
+		ch <- glog.Event{Severity: "INFO", Message: []byte(m)}
+	}
+	close(ch)
+
+	// Events are still written after a write fails
+	w := &failingWriter{n: 2}
+	err := jsonlog.Capture(w, jsonlog.Options{}, ch)
+	assert.EqualError(t, err, "2 events not written: broken pipe")
+	if assert.Len(t, w.lines, 1) {
+		assert.Contains(t, w.lines[0], `"message":"three"`)
+	}
+}
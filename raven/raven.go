@@ -89,6 +89,15 @@ const xSentryAuthTemplate = "Sentry sentry_version=2.0, sentry_client=raven-go/0
 // An iso8601 timestamp without the timezone. This is the format Sentry expects.
 const iso8601 = "2006-01-02T15:04:05"
 
+// EnvTags maps the names of tags set on every event to the environment
+// variables they are read from by NewClient. Tags whose variables are unset
+// are omitted, except job_name, which is lowercased and defaults to
+// "unknown".
+var EnvTags = map[string]string{
+	"job_name":    "KHAN_JOB_NAME",
+	"environment": "YEXT_SITE",
+}
+
 // NewClient creates a new client for a server identified by the given dsn
 // A dsn is a string in the form:
 //	{PROTOCOL}://{PUBLIC_KEY}:{SECRET_KEY}@{HOST}/{PATH}{PROJECT_ID}
@@ -126,16 +135,17 @@ func NewClient(dsn string) (client *Client, err error) {
 		return nil
 	}
 	m := make(map[string]string)
-	if os.Getenv("KHAN_JOB_NAME") != "" {
-		m["job_name"] = strings.ToLower(os.Getenv("KHAN_JOB_NAME"))
+	for tag, name := range EnvTags {
+		if v := os.Getenv(name); v != "" {
+			m[tag] = v
+		}
+	}
+	if jobName, ok := m["job_name"]; ok {
+		m["job_name"] = strings.ToLower(jobName)
 	} else {
 		m["job_name"] = "unknown"
 	}
 
-	if yextSite := os.Getenv("YEXT_SITE"); yextSite != "" {
-		m["environment"] = yextSite
-	}
-
 	return &Client{
 		URL:       u,
 		PublicKey: publicKey,
@@ -1,5 +1,7 @@
 package sentry
 
+import "github.com/yext/glog"
+
 // Contains attributes which can be passed to glog, which will be used
 // by this package to route and process Sentry errors accordingly.
 
@@ -20,6 +22,76 @@ func Fingerprint(print ...string) interface{} {
 	return fingerprint(print)
 }
 
+type fingerprintStrategy struct {
+	FingerprintStrategy
+}
+
+// FingerprintBy can be used as a glog attribute to build the fingerprint of
+// the event with the given strategy, even if fingerprinting is disabled.
+// An explicit Fingerprint takes precedence.
+func FingerprintBy(strategy FingerprintStrategy) interface{} {
+	return fingerprintStrategy{strategy}
+}
+
+type tag struct {
+	key, value string
+}
+
+// Tag can be used as a glog attribute to set a tag on the issue in Sentry.
+// Tags can also be matched by routing rules.
+func Tag(key, value string) interface{} {
+	return tag{key: key, value: value}
+}
+
+type traceParent string
+
+// TraceParent can be used as a glog attribute to link the event to the
+// trace identified by a W3C traceparent header, such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01". A
+// context.Context carrying an OpenTelemetry span can also be passed.
+func TraceParent(header string) interface{} {
+	return traceParent(header)
+}
+
+// Mechanism types set on Sentry exceptions, describing how the event
+// was captured.
+const (
+	// MechanismGlog is used for events logged at the ERROR level.
+	MechanismGlog = "glog"
+	// MechanismFatal is used for events logged at the FATAL level.
+	MechanismFatal = "fatal"
+	// MechanismPanic is used for recovered panics.
+	MechanismPanic = "panic"
+)
+
+type mechanism struct {
+	mechanismType string
+	handled       bool
+}
+
+var mechanismPanic = mechanism{mechanismType: MechanismPanic, handled: false}
+
+// Mechanism can be used as a glog attribute to override the mechanism type
+// and handled flag set on the issue in Sentry. By default, ERROR events are
+// handled with the "glog" mechanism, and FATAL events are unhandled with
+// the "fatal" mechanism.
+func Mechanism(mechanismType string, handled bool) interface{} {
+	return mechanism{mechanismType: mechanismType, handled: handled}
+}
+
+// captured marks a glog event which has already been sent to Sentry
+// directly, so that it is skipped when received over the glog channel.
+type captured struct{}
+
+func alreadyCaptured(e glog.Event) bool {
+	for _, d := range e.Data {
+		if _, ok := d.(captured); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // NoExceptionCleanup is an argument which, when passed on a glog event,
 // signifies that the exception tracebacks should not be cleaned up
 // and deduplicated.
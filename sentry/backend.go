@@ -13,26 +13,52 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/internal/formatstring"
 	"github.com/yext/glog-contrib/stacktrace"
 )
 
-// The maximum number of wrapped errors processed.
+// The default maximum number of wrapped errors processed.
 const maxErrorDepth = 10
 
+// The time allowed for sending a FATAL event before Fatal exits the process.
+const fatalFlushTimeout = 5 * time.Second
+
 var (
-	sentryDebug = flag.Bool("sentryDebug", false,
-		"enable debug mode in Sentry clients")
-	sentryFingerprinting = flag.Bool("sentryFingerprinting", false,
-		"enable server-side issue fingerprinting. If set, duplicate issues will only be tracked if they have equivalent filenames and line numbers")
+	// The defaults of Options, set by the flags of this package.
+	sentryDebug          bool
+	sentryFingerprinting bool
+	sentryThreadDumps    bool
 
 	hostname string
 )
 
+// RegisterFlags adds the -sentryDebug, -sentryFingerprinting and
+// -sentryThreadDumps flags to a flag set, for applications which parse
+// their own flag.FlagSet. Their values are used as the defaults of
+// Options.Debug, Options.Fingerprinting and Options.ThreadDumps. The flags
+// are registered on flag.CommandLine at init, and flags already defined in
+// the flag set are skipped.
+func RegisterFlags(fs *flag.FlagSet) {
+	boolVar := func(p *bool, name, usage string) {
+		if fs.Lookup(name) == nil {
+			fs.BoolVar(p, name, *p, usage)
+		}
+	}
+	boolVar(&sentryDebug, "sentryDebug",
+		"enable debug mode in Sentry clients")
+	boolVar(&sentryFingerprinting, "sentryFingerprinting",
+		"enable server-side issue fingerprinting. If set, duplicate issues will only be tracked if they have equivalent filenames and line numbers")
+	boolVar(&sentryThreadDumps, "sentryThreadDumps",
+		"attach the stacks of all goroutines to events sent by sentry.Fatal and to recovered panics")
+}
+
 func init() {
+	RegisterFlags(flag.CommandLine)
 	hostname, _ = os.Hostname()
 	if short := strings.Index(hostname, "."); short != -1 {
 		hostname = hostname[:short]
@@ -52,96 +78,400 @@ func init() {
 //		},
 //		glog.RegisterBackend())
 //
-// When an event is received via glog at the ERROR severity,
-// the first provided DSN will be used, unless a sentry.AltDsn is
-// tagged on the glog event, in which case the specified client
-// for that DSN will be used:
+// A DSN with a file scheme, such as "file:///tmp/sentry.jsonl", appends
+// events to the named file as lines of JSON instead of sending them.
+//
+// When an event is received via glog at the ERROR severity, the first
+// provided DSN will be used, unless
+// a sentry.AltDsn is tagged on the glog event, in which case the specified
+// client for that DSN will be used:
 //   glog.Error("error for secondary DSN", sentry.AltDsn("https://optionalSecondaryDsn"))
-func CaptureErrors(project string, dsns []string, opts sentry.ClientOptions, comm <-chan glog.Event) {
+//
+// Options override the defaults set by command line flags:
+//   sentry.CaptureErrors("projectName", dsns, clientOpts, glog.RegisterBackend(),
+//   	sentry.WithFingerprinting(true))
+func CaptureErrors(project string, dsns []string, opts sentry.ClientOptions, comm <-chan glog.Event, options ...Option) {
+	CaptureErrorsWithRouting(project, dsns, Routing{}, opts, comm, options...)
+}
+
+// CaptureErrorsWithRouting is CaptureErrors with rules which choose the DSNs
+// each event is sent to. Clients are also constructed for the DSNs named in
+// the rules. A sentry.AltDsn tagged on an event takes precedence over the
+// rules, and events which match no rule are sent to the routing default,
+// or the first provided DSN.
+func CaptureErrorsWithRouting(project string, dsns []string, routing Routing, opts sentry.ClientOptions, comm <-chan glog.Event, options ...Option) {
 	// If no DSNs specified, panic (we can't invoke glog)
 	if len(dsns) == 0 {
 		panic("must specify at least one Sentry DSN")
 	}
 	glog.Infof("running in local")
 
-	hubs := make(map[string]*sentry.Hub)
-	var primaryHub *sentry.Hub
-	for _, dsn := range dsns {
-		client, err := sentry.NewClient(buildClientOptions(dsn, opts))
+	b, err := NewBackend(Config{Project: project, DSNs: dsns, Routing: routing}, opts, options...)
 
-		// If unable to initialize the Sentry client, panic (we can't invoke glog)
-		if err != nil {
-			panic(err)
+	// If unable to initialize the Sentry clients, panic (we can't invoke glog)
+	if err != nil {
+		panic(err)
+	}
+
+	b.Run(comm)
+}
+
+// glog severities, in increasing order.
+var severities = []string{"INFO", "WARNING", "ERROR", "FATAL"}
+
+func severityRank(severity string) (int, error) {
+	for i, s := range severities {
+		if strings.EqualFold(s, severity) {
+			return i, nil
 		}
+	}
+	return 0, fmt.Errorf("unsupported severity %q", severity)
+}
+
+// hubSet contains the hubs created for a Config, keyed by DSN.
+type hubSet struct {
+	hubs    map[string]*sentry.Hub
+	primary *sentry.Hub
+	routing Routing
+
+	// files are the files written by DSNs with a file scheme.
+	files       map[string]*os.File
+	settings    clientSettings
+	build       sentry.Context
+	minSeverity int
+	options     Options
+}
 
-		// Initialize a Hub (which contains additional scope)
-		scope := sentry.NewScope()
-		hub := sentry.NewHub(client, scope)
+// newHubSet creates the hubs for the configuration. Hubs in prev, if any,
+// are reused for DSNs which are still configured with the same settings.
+func newHubSet(cfg Config, opts sentry.ClientOptions, options Options, prev *hubSet) (*hubSet, error) {
+	if len(cfg.DSNs) == 0 {
+		return nil, fmt.Errorf("must specify at least one Sentry DSN")
+	}
+	if err := cfg.Routing.init(); err != nil {
+		return nil, err
+	}
+	minSeverity, err := severityRank("ERROR")
+	if cfg.MinSeverity != "" {
+		minSeverity, err = severityRank(cfg.MinSeverity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Fingerprinting != nil {
+		options.Fingerprinting = *cfg.Fingerprinting
+	}
+	var build BuildRelease
+	if options.DetectRelease {
+		build = DetectRelease()
+	}
+	h := &hubSet{
+		hubs:        make(map[string]*sentry.Hub),
+		routing:     cfg.Routing,
+		files:       make(map[string]*os.File),
+		settings:    cfg.clientSettings(opts, options, build),
+		build:       build.context(options.BuildDependencies),
+		minSeverity: minSeverity,
+		options:     options,
+	}
+	for _, dsn := range appendUnique(nil, append(cfg.DSNs, cfg.Routing.dsns()...)...) {
+		hub, err := h.newHub(dsn, opts, prev)
+		if err != nil {
+			h.release(prev, 0)
+			return nil, err
+		}
 
 		// Set the first provided DSN as the primary hub
-		if primaryHub == nil {
-			primaryHub = hub
+		if h.primary == nil {
+			h.primary = hub
+		}
+		h.hubs[dsn] = hub
+	}
+	return h, nil
+}
+
+func (h *hubSet) newHub(dsn string, opts sentry.ClientOptions, prev *hubSet) (*sentry.Hub, error) {
+	if prev != nil && prev.settings == h.settings {
+		if hub, ok := prev.hubs[dsn]; ok {
+			if f, ok := prev.files[dsn]; ok {
+				h.files[dsn] = f
+			}
+			return hub, nil
+		}
+	}
+
+	clientOpts := buildClientOptions(dsn, opts, h.settings, h.options)
+
+	// DSNs with a file scheme write events to a local file instead
+	if path, ok := fileDsnPath(dsn); ok {
+		f, err := openFileDsn(path)
+		if err != nil {
+			return nil, err
 		}
+		h.files[dsn] = f
+		clientOpts.Dsn = ""
+		clientOpts.Transport = NewFileTransport(f)
+	}
 
-		// Configure the cleanup period for the newly initialized client
-		defer client.Flush(1 * time.Second)
+	client, err := sentry.NewClient(clientOpts)
+	if err != nil {
+		return nil, err
+	}
 
-		hubs[dsn] = hub
+	// Initialize a Hub (which contains additional scope)
+	scope := sentry.NewScope()
+	if h.build != nil {
+		scope.SetContext(buildContext, h.build)
 	}
+	return sentry.NewHub(client, scope), nil
+}
 
-	// This for loop runs indefinitely unless the glog channel closes
-	// (which should only happen on app exit)
-	for glogEvent := range comm {
-		if glogEvent.Severity == "ERROR" {
-			e, targetDsn := FromGlogEvent(glogEvent, true)
-			if hub, ok := hubs[targetDsn]; ok {
-				hub.CaptureEvent(e)
-			} else {
-				primaryHub.CaptureEvent(e)
-			}
+// release flushes the hubs which are not used by next, and closes their
+// files. If next is nil, all hubs are released.
+func (h *hubSet) release(next *hubSet, timeout time.Duration) {
+	for dsn, hub := range h.hubs {
+		if next != nil && next.hubs[dsn] == hub {
+			continue
+		}
+		hub.Flush(timeout)
+		if f, ok := h.files[dsn]; ok {
+			f.Close()
 		}
 	}
 }
 
-// Adds the dsn, server hostname, and debug status to the provided client options
-func buildClientOptions(dsn string, opts sentry.ClientOptions) sentry.ClientOptions {
-	opts.Dsn = dsn
-	if !opts.Debug {
-		opts.Debug = *sentryDebug
+// capture processes the glog event, if its severity is high enough, and
+// sends it to the hubs for its target DSN, or those chosen by the routing
+// rules, falling back to the primary hub. If flush is set, it waits for the
+// event to be sent.
+func (h *hubSet) capture(glogEvent glog.Event, flush bool) {
+	if rank, err := severityRank(glogEvent.Severity); err != nil || rank < h.minSeverity {
+		return
+	}
+	// Clients would send every event with a sample rate of 0.
+	if h.settings.sampleRate <= 0 {
+		return
+	}
+	e, targetDsn := fromGlogEvent(glogEvent, h.options)
+	match := &eventMatch{glogEvent: glogEvent, event: e, maxErrorDepth: h.options.MaxErrorDepth}
+	if o := h.ownership(); o != nil {
+		o.setTags(e, match.originTrace())
 	}
-	opts.ServerName = hostname
 
-	return opts
+	var hubs []*sentry.Hub
+	if hub, ok := h.hubs[targetDsn]; ok {
+		hubs = append(hubs, hub)
+	} else {
+		for _, dsn := range h.routing.route(match) {
+			hubs = append(hubs, h.hubs[dsn])
+		}
+	}
+	if len(hubs) == 0 {
+		hubs = append(hubs, h.primary)
+	}
+
+	// Copy the event before any client modifies it
+	events := []*sentry.Event{e}
+	for range hubs[1:] {
+		events = append(events, copyEvent(e))
+	}
+	var ids []sentry.EventID
+	for i, hub := range hubs {
+		if id := hub.CaptureEvent(events[i]); id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	// Record the events which were accepted, rather than dropped by
+	// sampling or event processors, on the span which produced them.
+	if link := traceFromData(glogEvent.Data); link != nil {
+		for _, id := range ids {
+			link.recordEvent(id)
+		}
+	}
+	if flush {
+		for _, hub := range hubs {
+			hub.Flush(fatalFlushTimeout)
+		}
+	}
 }
 
-// Builds a fingerprint of the filename, function, and line number for all
-// of the frames in the top (most important) exception stacktrace.
-func buildFingerprint(exceptions []sentry.Exception) []string {
-	var r []string
-	ex := exceptions[0]
-	for _, f := range ex.Stacktrace.Frames {
-		if f.InApp {
-			r = append(r, fmt.Sprintf("%s in %s at line %d", f.Filename, f.Function, f.Lineno))
+// ownership returns the Ownership used to set the owner tags of events:
+// that of the routing configuration, falling back to Options.Ownership.
+func (h *hubSet) ownership() *Ownership {
+	if h.routing.Ownership != nil {
+		return h.routing.Ownership
+	}
+	return h.options.Ownership
+}
+
+// copyEvent returns a copy of the event, so that it can be sent to another
+// DSN. Clients and event processors may modify any part of the events they
+// capture, so the slices, maps and pointers of the event are copied too.
+func copyEvent(e *sentry.Event) *sentry.Event {
+	c := *e
+	c.Tags = copyStrings(e.Tags)
+	c.Extra = copyValues(e.Extra)
+	c.Modules = copyStrings(e.Modules)
+	c.User.Data = copyStrings(e.User.Data)
+	c.Fingerprint = append([]string(nil), e.Fingerprint...)
+	if e.Contexts != nil {
+		c.Contexts = make(map[string]sentry.Context, len(e.Contexts))
+		for k, v := range e.Contexts {
+			c.Contexts[k] = copyValues(v)
+		}
+	}
+	if e.Breadcrumbs != nil {
+		c.Breadcrumbs = make([]*sentry.Breadcrumb, len(e.Breadcrumbs))
+		for i, b := range e.Breadcrumbs {
+			if b != nil {
+				copied := *b
+				copied.Data = copyValues(b.Data)
+				c.Breadcrumbs[i] = &copied
+			}
+		}
+	}
+	if e.Exception != nil {
+		c.Exception = make([]sentry.Exception, len(e.Exception))
+		for i, ex := range e.Exception {
+			ex.Stacktrace = copyStacktrace(ex.Stacktrace)
+			if ex.Mechanism != nil {
+				m := *ex.Mechanism
+				m.Data = copyValues(m.Data)
+				ex.Mechanism = &m
+			}
+			c.Exception[i] = ex
+		}
+	}
+	if e.Threads != nil {
+		c.Threads = make([]sentry.Thread, len(e.Threads))
+		for i, th := range e.Threads {
+			th.Stacktrace = copyStacktrace(th.Stacktrace)
+			c.Threads[i] = th
 		}
 	}
-	return r
+	if e.Request != nil {
+		r := *e.Request
+		r.Headers = copyStrings(r.Headers)
+		r.Env = copyStrings(r.Env)
+		c.Request = &r
+	}
+	return &c
+}
+
+func copyStacktrace(st *sentry.Stacktrace) *sentry.Stacktrace {
+	if st == nil {
+		return nil
+	}
+	c := &sentry.Stacktrace{
+		Frames:        append([]sentry.Frame(nil), st.Frames...),
+		FramesOmitted: append([]uint(nil), st.FramesOmitted...),
+	}
+	for i, f := range c.Frames {
+		c.Frames[i].PreContext = append([]string(nil), f.PreContext...)
+		c.Frames[i].PostContext = append([]string(nil), f.PostContext...)
+		c.Frames[i].Vars = copyValues(f.Vars)
+	}
+	return c
+}
+
+// copyStrings returns a copy of the map, or nil if it is nil.
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// copyValues returns a shallow copy of the map, or nil if it is nil.
+func copyValues(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+var activeBackend struct {
+	sync.RWMutex
+	backend *Backend
+}
+
+func setActiveBackend(b *Backend) {
+	activeBackend.Lock()
+	defer activeBackend.Unlock()
+	activeBackend.backend = b
+}
+
+// captureDirect sends the glog event to Sentry without going through the
+// glog channel, using the running Backend. If flush is set, it waits for
+// the event to be sent. It returns false if no Backend is running.
+func captureDirect(glogEvent glog.Event, flush bool) bool {
+	activeBackend.RLock()
+	b := activeBackend.backend
+	activeBackend.RUnlock()
+	if b == nil {
+		return false
+	}
+
+	b.capture(glogEvent, flush)
+	return true
+}
+
+// perClientTransport is implemented by transports which hold state for
+// a single DSN, and so must be copied for each client.
+type perClientTransport interface {
+	cloneTransport() sentry.Transport
+}
+
+// Adds the dsn, server hostname, and configured settings to the provided client options
+func buildClientOptions(dsn string, opts sentry.ClientOptions, settings clientSettings, options Options) sentry.ClientOptions {
+	opts.Dsn = dsn
+	if t, ok := opts.Transport.(perClientTransport); ok {
+		opts.Transport = t.cloneTransport()
+	}
+	opts.Debug = settings.debug
+	opts.SampleRate = settings.sampleRate
+	opts.Release = settings.release
+	opts.Dist = settings.dist
+	opts.Environment = settings.environment
+	opts.ServerName = options.Hostname
+
+	return opts
 }
 
 // FromGlogEvent processes a glog event and generates a corresponding Sentry event.
 // This includes building the stacktrace, cleaning up the error title and subtitle,
 // and identifying whether any TargetDSN or Fingerprint overrides were set.
 // If exceptionDedup is true, then the exception objects and their stacktraces
-// are deduplicated and merged.
-func FromGlogEvent(e glog.Event, exceptionDedup bool) (*sentry.Event, string) {
+// are deduplicated and merged. Options override the defaults set by command
+// line flags.
+func FromGlogEvent(e glog.Event, exceptionDedup bool, options ...Option) (*sentry.Event, string) {
+	return fromGlogEvent(e, buildOptions(append([]Option{WithDedup(exceptionDedup)}, options...)))
+}
+
+func fromGlogEvent(e glog.Event, o Options) (*sentry.Event, string) {
 	targetDsn := ""
+	mech := defaultMechanism(e.Severity)
+	var strategy FingerprintStrategy
+	if o.Fingerprinting {
+		strategy = o.FingerprintStrategy
+	}
 
 	s := sentry.NewEvent()
 	s.Message = removeGlogPrefixFromMessage(e.Message)
 	s.Level = buildLevel(e.Severity)
-	s.ServerName = hostname
+	s.ServerName = o.Hostname
 
 	s.Extra = map[string]interface{}{}
-	s.Logger = stacktrace.GopathRelativeFile(os.Args[0])
+	s.Logger = o.Logger
 
 	data := map[string]interface{}{}
 	sanitizedFormatString := ""
@@ -151,17 +481,23 @@ func FromGlogEvent(e glog.Event, exceptionDedup bool) (*sentry.Event, string) {
 			targetDsn = string(d.(altDsn))
 		case fingerprint:
 			s.Fingerprint = []string(d.(fingerprint))
+		case fingerprintStrategy:
+			strategy = t.FingerprintStrategy
+		case mechanism:
+			mech = t
+		case tag:
+			s.Tags[t.key] = t.value
 		case *http.Request:
 			s.Request = buildHttpRequest(t)
 		case map[string]interface{}:
 			for k, v := range t {
-				data[k] = v
+				addData(data, k, o.Serializer.Serialize(v))
 			}
 		case glog.FormatStringArg:
 			// If we have a format string arg, then we can use it
 			// to make a rough approximation of the error's "type"
 			// by removing the format characters (like %s).
-			sanitizedFormatString = cleanupFormatString(t.Format)
+			sanitizedFormatString = formatstring.Cleanup(t.Format)
 		case glog.ErrorArg:
 			// Prepend the Message with the innermost error message.
 			// This causes it to be used for the headline.
@@ -171,7 +507,7 @@ func FromGlogEvent(e glog.Event, exceptionDedup bool) (*sentry.Event, string) {
 			// Augment the stack trace of the call site with the stack trace in
 			// the error. Loop through and unwrap any chained errors.
 			err := t.Error
-			for i := 0; i < maxErrorDepth && err != nil; i++ {
+			for i := 0; i < o.MaxErrorDepth && err != nil; i++ {
 				errTrace := stacktrace.ExtractStacktrace(err)
 				fullMsg := prependMessage(headline(err), err.Error())
 
@@ -236,25 +572,77 @@ func FromGlogEvent(e glog.Event, exceptionDedup bool) (*sentry.Event, string) {
 	// Reverse the order of the Exception array
 	reverse(s.Exception)
 
-	if exceptionDedup {
+	if o.Dedup {
 		s.Exception = DedupExceptions(s.Exception)
 	}
 
-	// Set the fingerprint based on the stack trace, if option is specified.
-	// This overrides logic in Sentry which will take the specific error
-	// message in to account. It instead will be identified by the filename,
-	// method name, and line number.
-	if len(s.Fingerprint) == 0 && *sentryFingerprinting {
-		s.Fingerprint = buildFingerprint(s.Exception)
+	setMechanism(s.Exception, mech)
+
+	// Set the fingerprint with the strategy, if option or attribute is
+	// specified. This overrides logic in Sentry which will take the specific
+	// error message in to account. By default, it instead will be identified
+	// by the filename, method name, and line number.
+	if len(s.Fingerprint) == 0 && strategy != nil {
+		s.Fingerprint = strategy.Fingerprint(s, errorChain(e, o.MaxErrorDepth))
 	}
 
+	for _, key := range o.TagKeys {
+		if v, ok := data[key]; ok {
+			if _, set := s.Tags[key]; !set {
+				s.Tags[key] = tagValue(v)
+				delete(data, key)
+			}
+		}
+	}
 	if len(data) > 0 {
 		s.Extra["Data"] = data
 	}
 
+	// Attach the stacks of all goroutines, if the option is specified, so
+	// that deadlocks and crashes can be diagnosed. Recovered panics and
+	// FATAL events sent by Fatal are captured on the goroutine which caused
+	// them, which is marked as crashed.
+	if o.ThreadDumps && hasDumpThreads(e) {
+		s.Threads = buildThreads(e.StackTrace)
+	}
+
+	for _, enricher := range o.Enrichers {
+		enricher.Enrich(s)
+	}
+
+	// Link the event and the trace which produced it, if known.
+	if link := traceFromData(e.Data); link != nil {
+		link.apply(s)
+	}
+
 	return s, targetDsn
 }
 
+func defaultMechanism(severity string) mechanism {
+	if severity == "FATAL" {
+		return mechanism{mechanismType: MechanismFatal, handled: false}
+	}
+	return mechanism{mechanismType: MechanismGlog, handled: true}
+}
+
+// setMechanism sets the mechanism of each exception, and links them into a
+// chain in the order that Sentry displays them: the last (top-level)
+// exception is the root, and each exception's parent is the one after it.
+func setMechanism(e []sentry.Exception, m mechanism) {
+	for i := range e {
+		mech := &sentry.Mechanism{
+			Type:        m.mechanismType,
+			Handled:     &m.handled,
+			ExceptionID: len(e) - 1 - i,
+		}
+		if i < len(e)-1 {
+			parent := mech.ExceptionID - 1
+			mech.ParentID = &parent
+		}
+		e[i].Mechanism = mech
+	}
+}
+
 func reverse(e []sentry.Exception) {
 	for i := len(e)/2 - 1; i >= 0; i-- {
 		o := len(e) - 1 - i
@@ -9,7 +9,9 @@ import (
 	sentrygo "github.com/getsentry/sentry-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
 	"github.com/yext/yerrors"
+	"golang.org/x/xerrors"
 )
 
 func TestGlogSimpleEvent(t *testing.T) {
@@ -320,3 +322,46 @@ func TestGlogYerrorsWrappedEvent(t *testing.T) {
 	assert.Equal(t, errorWrappedLine, ex.Stacktrace.Frames[1].Lineno, "second frame line number matches")
 	assert.Equal(t, errorLine, ex.Stacktrace.Frames[2].Lineno, "third frame line number matches")
 }
+
+func TestGlogEventMechanism(t *testing.T) {
+	err := xerrors.Errorf("wrapped: %w", xerrors.New("inner"))
+	e, _ := sentry.FromGlogEvent(withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("test message"),
+		Data:     []interface{}{glog.ErrorArg{Error: err}},
+	}), false)
+
+	assert.Len(t, e.Exception, 3, "three exceptions")
+	for i, ex := range e.Exception {
+		assert.NotNil(t, ex.Mechanism)
+		assert.Equal(t, sentry.MechanismGlog, ex.Mechanism.Type)
+		assert.True(t, *ex.Mechanism.Handled, "glog errors are handled")
+		assert.Equal(t, len(e.Exception)-1-i, ex.Mechanism.ExceptionID)
+	}
+	assert.Equal(t, 1, *e.Exception[0].Mechanism.ParentID)
+	assert.Equal(t, 0, *e.Exception[1].Mechanism.ParentID)
+	assert.Nil(t, e.Exception[2].Mechanism.ParentID, "the top-level exception is the root")
+}
+
+func TestFatalEventMechanism(t *testing.T) {
+	e, _ := sentry.FromGlogEvent(withCallers(glog.Event{
+		Severity: "FATAL",
+		Message:  []byte("test message"),
+	}), true)
+
+	assert.Len(t, e.Exception, 1, "one exception")
+	assert.Equal(t, sentry.MechanismFatal, e.Exception[0].Mechanism.Type)
+	assert.False(t, *e.Exception[0].Mechanism.Handled, "fatal errors are unhandled")
+}
+
+func TestMechanismOverride(t *testing.T) {
+	e, _ := sentry.FromGlogEvent(withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("test message"),
+		Data:     []interface{}{sentry.Mechanism("custom", false)},
+	}), true)
+
+	assert.Len(t, e.Exception, 1, "one exception")
+	assert.Equal(t, "custom", e.Exception[0].Mechanism.Type)
+	assert.False(t, *e.Exception[0].Mechanism.Handled)
+}
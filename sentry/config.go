@@ -0,0 +1,279 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/yext/glog"
+	"gopkg.in/yaml.v3"
+)
+
+// Config configures a Backend. It can be loaded from a YAML or JSON file
+// with LoadConfig, or from the environment with ConfigFromEnv, and replaced
+// while the backend is running with Backend.Reload.
+type Config struct {
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+	// DSNs are the Sentry DSNs events are sent to. The first is the
+	// primary DSN, which events are sent to unless they are routed elsewhere.
+	DSNs []string `json:"dsns" yaml:"dsns"`
+	// Routing chooses the DSNs each event is sent to.
+	Routing Routing `json:"routing,omitempty" yaml:"routing,omitempty"`
+
+	// MinSeverity is the lowest glog severity which is sent to Sentry:
+	// INFO, WARNING, ERROR or FATAL. Defaults to ERROR. glog exits before
+	// FATAL events reach the backend, so they are only sent by Fatal.
+	MinSeverity string `json:"minSeverity,omitempty" yaml:"minSeverity,omitempty"`
+	// SampleRate is the fraction of events which are sent, between 0 and 1.
+	// A rate of 0 sends no events. If unset, the sample rate of the client
+	// options is used.
+	SampleRate *float64 `json:"sampleRate,omitempty" yaml:"sampleRate,omitempty"`
+	// Fingerprinting overrides Options.Fingerprinting, if set.
+	Fingerprinting *bool `json:"fingerprinting,omitempty" yaml:"fingerprinting,omitempty"`
+	// Debug overrides Options.Debug, if set.
+	Debug *bool `json:"debug,omitempty" yaml:"debug,omitempty"`
+	// Release and Environment override the client options, if set.
+	Release     string `json:"release,omitempty" yaml:"release,omitempty"`
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"`
+}
+
+// LoadConfig reads a Config from a file. Files with a .json extension are
+// decoded as JSON, and all others as YAML.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(b, &cfg)
+	} else {
+		err = yaml.Unmarshal(b, &cfg)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// ConfigFromEnv reads a Config from the environment variables SENTRY_PROJECT,
+// SENTRY_DSN (a comma-separated list), SENTRY_MIN_SEVERITY,
+// SENTRY_SAMPLE_RATE, SENTRY_FINGERPRINTING, SENTRY_DEBUG, SENTRY_RELEASE
+// and SENTRY_ENVIRONMENT. Routing can only be configured in a file.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Project:     os.Getenv("SENTRY_PROJECT"),
+		MinSeverity: os.Getenv("SENTRY_MIN_SEVERITY"),
+		Release:     os.Getenv("SENTRY_RELEASE"),
+		Environment: os.Getenv("SENTRY_ENVIRONMENT"),
+	}
+	for _, dsn := range strings.Split(os.Getenv("SENTRY_DSN"), ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			cfg.DSNs = append(cfg.DSNs, dsn)
+		}
+	}
+
+	if v := os.Getenv("SENTRY_SAMPLE_RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("SENTRY_SAMPLE_RATE: %v", err)
+		}
+		cfg.SampleRate = &rate
+	}
+	for name, field := range map[string]**bool{
+		"SENTRY_FINGERPRINTING": &cfg.Fingerprinting,
+		"SENTRY_DEBUG":          &cfg.Debug,
+	} {
+		if v := os.Getenv(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %v", name, err)
+			}
+			*field = &b
+		}
+	}
+	return cfg, nil
+}
+
+// clientSettings are the settings of a Config which are applied to the
+// client options, so that clients must be recreated when they change.
+type clientSettings struct {
+	sampleRate  float64
+	debug       bool
+	release     string
+	dist        string
+	environment string
+}
+
+// clientSettings returns the settings for the client options, falling back
+// to the detected release if none is set.
+func (c Config) clientSettings(opts sentry.ClientOptions, options Options, build BuildRelease) clientSettings {
+	s := clientSettings{
+		sampleRate:  opts.SampleRate,
+		debug:       opts.Debug || options.Debug,
+		release:     opts.Release,
+		dist:        opts.Dist,
+		environment: opts.Environment,
+	}
+	// Clients treat a sample rate of 0 as 1, so only a rate set in the
+	// Config can disable events.
+	if s.sampleRate == 0 {
+		s.sampleRate = 1
+	}
+	if c.SampleRate != nil {
+		s.sampleRate = *c.SampleRate
+	}
+	if c.Debug != nil {
+		s.debug = *c.Debug
+	}
+	if c.Release != "" {
+		s.release = c.Release
+	}
+	if c.Environment != "" {
+		s.environment = c.Environment
+	}
+	if s.release == "" {
+		s.release = build.Release
+	}
+	if s.dist == "" {
+		s.dist = build.Dist
+	}
+	return s
+}
+
+// Backend sends glog events to Sentry using a Config which can be replaced
+// at runtime. Clients are only recreated for DSNs which are added, or when
+// client settings change, and clients which are no longer used are flushed.
+type Backend struct {
+	opts    sentry.ClientOptions
+	options Options
+
+	// reload serializes calls to Reload.
+	reload sync.Mutex
+	// mu is held for reading while an event is captured, so that hubs are
+	// not closed while they are in use.
+	mu   sync.RWMutex
+	hubs *hubSet
+}
+
+// NewBackend creates the Sentry clients for the configuration. The client
+// options are used for every client, and should not specify a DSN.
+// Options override the defaults set by command line flags, and are in turn
+// overridden by the configuration.
+func NewBackend(cfg Config, opts sentry.ClientOptions, options ...Option) (*Backend, error) {
+	o := buildOptions(options)
+	hubs, err := newHubSet(cfg, opts, o, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{opts: opts, options: o, hubs: hubs}, nil
+}
+
+// Reload replaces the configuration of the backend. If the configuration
+// is invalid, an error is returned and the previous one is kept.
+func (b *Backend) Reload(cfg Config) error {
+	b.reload.Lock()
+	defer b.reload.Unlock()
+
+	b.mu.RLock()
+	prev := b.hubs
+	b.mu.RUnlock()
+
+	hubs, err := newHubSet(cfg, b.opts, b.options, prev)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.hubs = hubs
+	b.mu.Unlock()
+
+	prev.release(hubs, time.Second)
+	return nil
+}
+
+// WatchFile reloads the configuration whenever the file is modified,
+// checking every interval. Errors are logged at the WARNING level, and the
+// previous configuration kept. The returned function stops watching.
+func (b *Backend) WatchFile(path string, interval time.Duration) (stop func()) {
+	modified := func() time.Time {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	last := modified()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if mod := modified(); !mod.IsZero() && !mod.Equal(last) {
+				last = mod
+				cfg, err := LoadConfig(path)
+				if err == nil {
+					err = b.Reload(cfg)
+				}
+				if err != nil {
+					glog.Warningf("sentry: unable to reload configuration: %v", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Run sends events from the glog channel to Sentry until it is closed,
+// which should only happen on app exit, then flushes all clients.
+func (b *Backend) Run(comm <-chan glog.Event) {
+	// Make the backend available to events which are not received over the
+	// glog channel, such as recovered panics.
+	setActiveBackend(b)
+	defer setActiveBackend(nil)
+
+	for glogEvent := range comm {
+		if alreadyCaptured(glogEvent) {
+			continue
+		}
+		b.capture(glogEvent, false)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.hubs.release(nil, time.Second)
+}
+
+// Flush waits until all events have been sent, or the timeout elapses,
+// and returns false in that case.
+func (b *Backend) Flush(timeout time.Duration) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ok := true
+	for _, hub := range b.hubs.hubs {
+		ok = hub.Flush(timeout) && ok
+	}
+	return ok
+}
+
+func (b *Backend) capture(glogEvent glog.Event, flush bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.hubs.capture(glogEvent, flush)
+}
@@ -0,0 +1,194 @@
+package sentry_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "sentry.yaml")
+	jsonPath := filepath.Join(dir, "sentry.json")
+	assert.NoError(t, os.WriteFile(yamlPath, []byte(`
+project: example
+dsns: [https://public@sentry.example.com/1]
+minSeverity: FATAL
+sampleRate: 0.5
+fingerprinting: true
+routing:
+  rules:
+    - package: github.com/yext/platform
+      dsns: [https://public@sentry.example.com/2]
+`), 0o644))
+	assert.NoError(t, os.WriteFile(jsonPath, []byte(`{
+  "project": "example",
+  "dsns": ["https://public@sentry.example.com/1"],
+  "minSeverity": "FATAL",
+  "sampleRate": 0.5,
+  "fingerprinting": true,
+  "routing": {"rules": [{"package": "github.com/yext/platform", "dsns": ["https://public@sentry.example.com/2"]}]}
+}`), 0o644))
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		cfg, err := sentry.LoadConfig(path)
+		assert.NoError(t, err, path)
+		assert.Equal(t, "example", cfg.Project)
+		assert.Equal(t, []string{"https://public@sentry.example.com/1"}, cfg.DSNs)
+		assert.Equal(t, "FATAL", cfg.MinSeverity)
+		if assert.NotNil(t, cfg.SampleRate) {
+			assert.Equal(t, 0.5, *cfg.SampleRate)
+		}
+		if assert.NotNil(t, cfg.Fingerprinting) {
+			assert.True(t, *cfg.Fingerprinting)
+		}
+		assert.Nil(t, cfg.Debug)
+		if assert.Len(t, cfg.Routing.Rules, 1) {
+			assert.Equal(t, "github.com/yext/platform", cfg.Routing.Rules[0].Package)
+		}
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("SENTRY_DSN", "https://public@sentry.example.com/1, https://public@sentry.example.com/2")
+	t.Setenv("SENTRY_SAMPLE_RATE", "0.25")
+	t.Setenv("SENTRY_DEBUG", "true")
+	t.Setenv("SENTRY_ENVIRONMENT", "staging")
+
+	cfg, err := sentry.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://public@sentry.example.com/1", "https://public@sentry.example.com/2"}, cfg.DSNs)
+	if assert.NotNil(t, cfg.SampleRate) {
+		assert.Equal(t, 0.25, *cfg.SampleRate)
+	}
+	if assert.NotNil(t, cfg.Debug) {
+		assert.True(t, *cfg.Debug)
+	}
+	assert.Nil(t, cfg.Fingerprinting)
+	assert.Equal(t, "staging", cfg.Environment)
+
+	t.Setenv("SENTRY_DEBUG", "sometimes")
+	_, err = sentry.ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestBackendReload(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "events.jsonl")
+
+	b, err := sentry.NewBackend(sentry.Config{DSNs: []string{server.DSN("a"), "file://" + file}}, sentrygo.ClientOptions{},
+		sentry.WithThreadDumps(true))
+	assert.NoError(t, err)
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		b.Run(comm)
+		close(done)
+	}()
+
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("before reload")})
+	comm <- withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("to file"),
+		Data:     []interface{}{sentry.AltDsn("file://" + file)},
+	})
+	server.RequireEvents(t, 1)
+
+	// Invalid configurations are rejected
+	assert.Error(t, b.Reload(sentry.Config{}))
+	assert.Error(t, b.Reload(sentry.Config{DSNs: []string{server.DSN("b")}, MinSeverity: "SEVERE"}))
+
+	assert.NoError(t, b.Reload(sentry.Config{DSNs: []string{server.DSN("b")}, MinSeverity: "FATAL"}))
+
+	// The removed file DSN was flushed and closed
+	contents, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	events := readEvents(t, contents)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "to file", events[0].Message)
+	}
+
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("below minimum severity")})
+	comm <- withCallers(glog.Event{Severity: "FATAL", Message: []byte("after reload")})
+	close(comm)
+	<-done
+
+	assert.Len(t, server.ProjectEvents("a"), 1)
+	if b := server.ProjectEvents("b"); assert.Len(t, b, 1) {
+		assert.Equal(t, "after reload", b[0].Message)
+	}
+}
+
+func TestBackendWatchFile(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "sentry.yaml")
+	writeConfig := func(project string, modified time.Time) {
+		assert.NoError(t, os.WriteFile(path, []byte("dsns: ["+server.DSN(project)+"]\n"), 0o644))
+		assert.NoError(t, os.Chtimes(path, modified, modified))
+	}
+
+	writeConfig("a", time.Now().Add(-time.Minute))
+	cfg, err := sentry.LoadConfig(path)
+	assert.NoError(t, err)
+	b, err := sentry.NewBackend(cfg, sentrygo.ClientOptions{})
+	assert.NoError(t, err)
+	stop := b.WatchFile(path, 10*time.Millisecond)
+	defer stop()
+
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		b.Run(comm)
+		close(done)
+	}()
+	defer func() {
+		close(comm)
+		<-done
+	}()
+
+	writeConfig("b", time.Now())
+	for i := 0; i < 100 && len(server.ProjectEvents("b")) == 0; i++ {
+		comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+		b.Flush(time.Second)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NotEmpty(t, server.ProjectEvents("b"))
+}
+
+func TestConfigSampleRateZero(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	rate := 0.0
+	b, err := sentry.NewBackend(sentry.Config{DSNs: []string{server.DSN("1")}, SampleRate: &rate},
+		sentrygo.ClientOptions{})
+	assert.NoError(t, err)
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		b.Run(comm)
+		close(done)
+	}()
+
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("disabled")})
+	// Without a sample rate in the Config, that of the client options is
+	// used, which sends every event if unset.
+	assert.NoError(t, b.Reload(sentry.Config{DSNs: []string{server.DSN("1")}}))
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("enabled")})
+	close(comm)
+	<-done
+
+	if events := server.Events(); assert.Len(t, events, 1) {
+		assert.Equal(t, "enabled", events[0].Message)
+	}
+}
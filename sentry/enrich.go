@@ -0,0 +1,257 @@
+package sentry
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Enrichers which add information about the process and the host it is
+// running on to Sentry events, as contexts and tags.
+
+// An Enricher adds information to Sentry events.
+type Enricher interface {
+	Enrich(e *sentry.Event)
+}
+
+// EnricherFunc is a function which implements Enricher.
+type EnricherFunc func(e *sentry.Event)
+
+// Enrich calls f.
+func (f EnricherFunc) Enrich(e *sentry.Event) {
+	f(e)
+}
+
+// DefaultKubernetesEnv maps keys of the kubernetes context to the
+// environment variables conventionally set from the downward API.
+var DefaultKubernetesEnv = map[string]string{
+	"pod":       "POD_NAME",
+	"namespace": "POD_NAMESPACE",
+	"node":      "NODE_NAME",
+	"pod_ip":    "POD_IP",
+}
+
+// DefaultEnrichers returns the enrichers used unless others are set with
+// WithEnrichers.
+func DefaultEnrichers() []Enricher {
+	return []Enricher{
+		RuntimeEnricher,
+		OSEnricher,
+		ContainerEnricher,
+		EnvContextEnricher("kubernetes", DefaultKubernetesEnv),
+		UptimeEnricher,
+	}
+}
+
+var (
+	// RuntimeEnricher adds the Go version, GOMAXPROCS, number of goroutines
+	// and memory statistics to the runtime context. Reading the memory
+	// statistics stops the world, so they are read at most once every
+	// memStatsInterval.
+	RuntimeEnricher Enricher = EnricherFunc(enrichRuntime)
+
+	// OSEnricher adds the operating system, distribution and kernel version
+	// to the os context.
+	OSEnricher Enricher = EnricherFunc(enrichOS)
+
+	// ContainerEnricher adds the ID of the container the process is running
+	// in, detected from its cgroup, to the container context.
+	ContainerEnricher Enricher = EnricherFunc(enrichContainer)
+
+	// UptimeEnricher adds the start time and uptime of the process to the
+	// app context. The start time is when this package was initialized.
+	UptimeEnricher Enricher = EnricherFunc(enrichUptime)
+)
+
+// EnvContextEnricher adds the values of environment variables to a
+// context. The mapping is from context keys to variable names, and unset
+// variables are omitted. For example, to add Kubernetes labels:
+//
+//	sentry.EnvContextEnricher("kubernetes", map[string]string{"app": "APP_LABEL"})
+func EnvContextEnricher(context string, mapping map[string]string) Enricher {
+	return EnricherFunc(func(e *sentry.Event) {
+		values := map[string]interface{}{}
+		for key, name := range mapping {
+			if v := os.Getenv(name); v != "" {
+				values[key] = v
+			}
+		}
+		mergeContext(e, context, values)
+	})
+}
+
+// EnvTagEnricher sets tags to the values of environment variables. The
+// mapping is from tag names to variable names, and unset variables are
+// omitted. Tags which are already set are kept.
+func EnvTagEnricher(mapping map[string]string) Enricher {
+	return EnricherFunc(func(e *sentry.Event) {
+		for tag, name := range mapping {
+			if v := os.Getenv(name); v != "" {
+				if _, ok := e.Tags[tag]; !ok {
+					e.Tags[tag] = v
+				}
+			}
+		}
+	})
+}
+
+// mergeContext adds the values to the named context of the event, keeping
+// any values which are already set.
+func mergeContext(e *sentry.Event, name string, values map[string]interface{}) {
+	if len(values) == 0 {
+		return
+	}
+	if e.Contexts == nil {
+		e.Contexts = map[string]sentry.Context{}
+	}
+	ctx := e.Contexts[name]
+	if ctx == nil {
+		ctx = sentry.Context{}
+		e.Contexts[name] = ctx
+	}
+	for k, v := range values {
+		if _, ok := ctx[k]; !ok {
+			ctx[k] = v
+		}
+	}
+}
+
+// memStatsInterval is how long memory statistics are reused for.
+const memStatsInterval = 10 * time.Second
+
+// The most recently read memory statistics.
+var memStats struct {
+	sync.Mutex
+	read  time.Time
+	stats runtime.MemStats
+}
+
+// readMemStats returns the memory statistics, reading them if they are
+// older than memStatsInterval.
+func readMemStats() runtime.MemStats {
+	memStats.Lock()
+	defer memStats.Unlock()
+	if time.Since(memStats.read) >= memStatsInterval {
+		runtime.ReadMemStats(&memStats.stats)
+		memStats.read = time.Now()
+	}
+	return memStats.stats
+}
+
+func enrichRuntime(e *sentry.Event) {
+	m := readMemStats()
+
+	// The keys match those set by the sentry-go client.
+	mergeContext(e, "runtime", map[string]interface{}{
+		"name":           "go",
+		"version":        runtime.Version(),
+		"go_maxprocs":    runtime.GOMAXPROCS(0),
+		"go_numroutines": runtime.NumGoroutine(),
+		"memstats": map[string]interface{}{
+			"alloc":          m.Alloc,
+			"sys":            m.Sys,
+			"heap_alloc":     m.HeapAlloc,
+			"heap_inuse":     m.HeapInuse,
+			"heap_objects":   m.HeapObjects,
+			"stack_inuse":    m.StackInuse,
+			"num_gc":         m.NumGC,
+			"pause_total_ns": m.PauseTotalNs,
+		},
+	})
+}
+
+// Information about the host which does not change while running.
+var host struct {
+	once         sync.Once
+	distribution string
+	kernel       string
+	containerID  string
+}
+
+func loadHost() {
+	host.once.Do(func() {
+		if b, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+			host.kernel = strings.TrimSpace(string(b))
+		}
+		host.distribution = osDistribution("/etc/os-release")
+		host.containerID = containerID("/proc/self/cgroup")
+		if host.containerID == "" {
+			host.containerID = containerID("/proc/self/mountinfo")
+		}
+	})
+}
+
+func enrichOS(e *sentry.Event) {
+	loadHost()
+	values := map[string]interface{}{"name": runtime.GOOS}
+	if host.distribution != "" {
+		values["distribution"] = host.distribution
+	}
+	if host.kernel != "" {
+		values["kernel_version"] = host.kernel
+	}
+	mergeContext(e, "os", values)
+}
+
+// osDistribution returns the PRETTY_NAME from an os-release file.
+func osDistribution(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v := strings.TrimPrefix(scanner.Text(), "PRETTY_NAME="); v != scanner.Text() {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+func enrichContainer(e *sentry.Event) {
+	loadHost()
+	if host.containerID != "" {
+		mergeContext(e, "container", map[string]interface{}{"id": host.containerID})
+	}
+}
+
+// Container IDs are 64 hex characters, found in cgroup paths such as
+// "/docker/<id>", "/kubepods/burstable/pod<uid>/<id>" or
+// "/system.slice/docker-<id>.scope", or in the mount of /etc/hostname.
+var containerIDRe = regexp.MustCompile(`(?:^|[/-])([0-9a-f]{64})(?:\.scope)?(?:/|$)`)
+
+// containerID returns the first container ID found in the file.
+func containerID(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			if m := containerIDRe.FindStringSubmatch(field); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+var processStart = time.Now()
+
+func enrichUptime(e *sentry.Event) {
+	mergeContext(e, "app", map[string]interface{}{
+		"app_start_time": processStart.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(processStart).Seconds()),
+	})
+}
@@ -0,0 +1,58 @@
+package sentry_test
+
+import (
+	"runtime"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+)
+
+func enrich(options ...sentry.Option) *sentrygo.Event {
+	s, _ := sentry.FromGlogEvent(withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")}), true, options...)
+	return s
+}
+
+func TestDefaultEnrichers(t *testing.T) {
+	t.Setenv("POD_NAME", "server-7d9f8-x2x4z")
+	t.Setenv("POD_NAMESPACE", "production")
+	t.Setenv("NODE_NAME", "")
+
+	s := enrich()
+	if assert.Contains(t, s.Contexts, "runtime") {
+		assert.Equal(t, runtime.Version(), s.Contexts["runtime"]["version"])
+		assert.NotZero(t, s.Contexts["runtime"]["go_numroutines"])
+		assert.Contains(t, s.Contexts["runtime"]["memstats"], "heap_alloc")
+	}
+	if assert.Contains(t, s.Contexts, "os") {
+		assert.Equal(t, runtime.GOOS, s.Contexts["os"]["name"])
+	}
+	assert.Equal(t, sentrygo.Context{"pod": "server-7d9f8-x2x4z", "namespace": "production"}, s.Contexts["kubernetes"])
+	if assert.Contains(t, s.Contexts, "app") {
+		assert.NotEmpty(t, s.Contexts["app"]["app_start_time"])
+	}
+}
+
+func TestWithEnrichers(t *testing.T) {
+	t.Setenv("KHAN_JOB_NAME", "indexer")
+	t.Setenv("YEXT_SITE", "")
+	t.Setenv("APP_LABEL", "search")
+
+	s := enrich(sentry.WithEnrichers(
+		sentry.EnvTagEnricher(map[string]string{"job_name": "KHAN_JOB_NAME", "environment": "YEXT_SITE"}),
+		sentry.EnvContextEnricher("kubernetes", map[string]string{"app": "APP_LABEL"}),
+		sentry.EnricherFunc(func(e *sentrygo.Event) { e.Tags["custom"] = "value" }),
+	))
+	assert.Equal(t, map[string]string{"job_name": "indexer", "custom": "value"}, s.Tags)
+	assert.Equal(t, map[string]sentrygo.Context{"kubernetes": {"app": "search"}}, s.Contexts)
+
+	// Tags set on the event are kept
+	s, _ = sentry.FromGlogEvent(withCallers(glog.Event{Severity: "ERROR", Message: []byte("message"),
+		Data: []interface{}{sentry.Tag("job_name", "explicit")}}), true,
+		sentry.WithEnrichers(sentry.EnvTagEnricher(map[string]string{"job_name": "KHAN_JOB_NAME"})))
+	assert.Equal(t, "explicit", s.Tags["job_name"])
+
+	assert.Empty(t, enrich(sentry.WithEnrichers()).Contexts)
+}
@@ -0,0 +1,48 @@
+package sentry
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/yext/glog"
+)
+
+// glog exits the process as soon as a FATAL message is logged, before it is
+// passed to any backends, so FATAL events can only be sent to Sentry by
+// capturing them before calling glog.
+
+// Fatal sends a FATAL event to Sentry with the running Backend, and waits
+// for it to be sent, then logs the arguments with glog.Fatal, which exits
+// the process. Errors among the arguments are attached to the event as
+// exceptions. With thread dumps enabled, the stacks of all goroutines are
+// attached, with the calling goroutine marked as crashed.
+func Fatal(args ...interface{}) {
+	reportFatal(fmt.Sprint(args...), args)
+	glog.FatalWithDepth(1, args...)
+}
+
+// Fatalf is Fatal with a format string, logged with glog.Fatalf.
+func Fatalf(format string, args ...interface{}) {
+	reportFatal(fmt.Sprintf(format, args...), args)
+	glog.FatalfWithDepth(1, format, args...)
+}
+
+// reportFatal must be called directly by Fatal or Fatalf, since the stack
+// trace of the event starts at their caller.
+func reportFatal(message string, args []interface{}) {
+	callers := make([]uintptr, 64)
+	stack := callers[:runtime.Callers(3, callers)]
+
+	data := []interface{}{dumpThreads{}}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			data = append(data, glog.ErrorArg{Error: err})
+		}
+	}
+	captureDirect(glog.Event{
+		Severity:   "FATAL",
+		Message:    []byte(message),
+		Data:       data,
+		StackTrace: stack,
+	}, true)
+}
@@ -0,0 +1,71 @@
+package sentry_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+)
+
+// fatalFileEnv is set to the path of a file DSN when the test binary is run
+// by TestFatal, to call sentry.Fatal in a process which can exit.
+const fatalFileEnv = "SENTRY_TEST_FATAL_FILE"
+
+func TestFatal(t *testing.T) {
+	if path := os.Getenv(fatalFileEnv); path != "" {
+		runFatal(path)
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "sentry.jsonl")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatal$")
+	cmd.Env = append(os.Environ(), fatalFileEnv+"="+path)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	require.True(t, errors.As(err, &exit), "the process exits: %v\n%s", err, out)
+	assert.Equal(t, 255, exit.ExitCode(), "glog.Fatal exits with 255")
+	assert.Contains(t, string(out), "unable to start: connection refused", "the message is logged")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	events := readEvents(t, contents)
+	require.Len(t, events, 1, "the event is sent before exiting")
+	e := events[0]
+	assert.Equal(t, sentrygo.LevelFatal, e.Level)
+	assert.Contains(t, e.Message, "unable to start: connection refused")
+	if assert.NotEmpty(t, e.Exception) {
+		assert.Equal(t, sentry.MechanismFatal, e.Exception[0].Mechanism.Type)
+	}
+	var crashed []string
+	for _, th := range e.Threads {
+		if th.Crashed {
+			for _, f := range th.Stacktrace.Frames {
+				crashed = append(crashed, f.Function)
+			}
+		}
+	}
+	assert.Contains(t, crashed, "runFatal", "the calling goroutine is marked as crashed")
+}
+
+// runFatal sends a FATAL event to the file with sentry.Fatalf, and exits.
+func runFatal(path string) {
+	b, err := sentry.NewBackend(sentry.Config{DSNs: []string{"file://" + path}}, sentrygo.ClientOptions{},
+		sentry.WithThreadDumps(true))
+	if err != nil {
+		panic(err)
+	}
+	comm := make(chan glog.Event)
+	go b.Run(comm)
+	// The backend is running once it has received an event, which is
+	// below the minimum severity.
+	comm <- glog.Event{Severity: "INFO", Message: []byte("started")}
+
+	sentry.Fatalf("unable to start: %v", errors.New("connection refused"))
+}
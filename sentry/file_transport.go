@@ -0,0 +1,75 @@
+package sentry
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// A transport which writes events locally instead of sending them to
+// Sentry, for use in development and tests.
+
+const fileDsnScheme = "file://"
+
+// FileTransport is a sentry.Transport which writes each event to a writer
+// as a single line of JSON, in the form it would be sent to Sentry.
+//
+// CaptureErrors uses a FileTransport for any DSN with a file scheme, which
+// appends events to the named file, for example "file:///tmp/sentry.jsonl".
+type FileTransport struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewFileTransport creates a FileTransport which writes events to w.
+func NewFileTransport(w io.Writer) *FileTransport {
+	return &FileTransport{w: w}
+}
+
+// Configure is a no-op, since the destination is set on creation.
+func (t *FileTransport) Configure(options sentry.ClientOptions) {}
+
+// SendEvent writes the event as a line of JSON.
+func (t *FileTransport) SendEvent(event *sentry.Event) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		sentry.Logger.Printf("file transport: unable to encode event %s: %v", event.EventID, err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.w.Write(append(b, '\n')); err != nil {
+		sentry.Logger.Printf("file transport: unable to write event %s: %v", event.EventID, err)
+	}
+}
+
+// Flush returns immediately, since events are written synchronously.
+func (t *FileTransport) Flush(timeout time.Duration) bool {
+	return true
+}
+
+// fileDsnPath returns the path of a DSN with a file scheme.
+func fileDsnPath(dsn string) (string, bool) {
+	if !strings.HasPrefix(dsn, fileDsnScheme) {
+		return "", false
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.Path == "" {
+		return strings.TrimPrefix(dsn, fileDsnScheme), true
+	}
+	return u.Path, true
+}
+
+// openFileDsn opens the file named by a DSN with a file scheme for appending.
+// Events may contain sensitive data, so it is created only accessible by the
+// current user.
+func openFileDsn(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+}
@@ -0,0 +1,85 @@
+package sentry_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+)
+
+// readEvents reads the events written by a FileTransport.
+func readEvents(t *testing.T, b []byte) []sentrygo.Event {
+	var events []sentrygo.Event
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		var e sentrygo.Event
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestFileTransport(t *testing.T) {
+	var buf bytes.Buffer
+	client, err := sentrygo.NewClient(sentrygo.ClientOptions{Transport: sentry.NewFileTransport(&buf)})
+	assert.NoError(t, err)
+
+	client.CaptureMessage("first message", nil, nil)
+	client.CaptureMessage("second message", nil, nil)
+	assert.True(t, client.Flush(0))
+
+	events := readEvents(t, buf.Bytes())
+	assert.Len(t, events, 2)
+	assert.Equal(t, "first message", events[0].Message)
+	assert.Equal(t, "second message", events[1].Message)
+}
+
+func TestCaptureErrorsFileDsn(t *testing.T) {
+	dir := t.TempDir()
+	primary := filepath.Join(dir, "primary.jsonl")
+	secondary := filepath.Join(dir, "secondary.jsonl")
+
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		sentry.CaptureErrors("example",
+			[]string{"file://" + primary, "file://" + secondary},
+			sentrygo.ClientOptions{},
+			comm)
+		close(done)
+	}()
+
+	comm <- withCallers(glog.Event{Severity: "INFO", Message: []byte("ignored message")})
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("primary message")})
+	comm <- withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("secondary message"),
+		Data:     []interface{}{sentry.AltDsn("file://" + secondary)},
+	})
+	close(comm)
+	<-done
+
+	info, err := os.Stat(primary)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "events are private to the user")
+
+	b, err := os.ReadFile(primary)
+	assert.NoError(t, err)
+	events := readEvents(t, b)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "primary message", events[0].Message)
+	assert.NotEmpty(t, events[0].Exception)
+
+	b, err = os.ReadFile(secondary)
+	assert.NoError(t, err)
+	events = readEvents(t, b)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "secondary message", events[0].Message)
+}
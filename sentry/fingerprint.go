@@ -0,0 +1,139 @@
+package sentry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Strategies for building the fingerprints which Sentry uses to group
+// events into issues.
+// See: https://docs.sentry.io/product/issues/grouping-and-fingerprints/
+
+// A FingerprintStrategy builds the fingerprint of an event, given the
+// errors passed to glog and the errors they wrap, outermost first.
+// An empty fingerprint leaves grouping to Sentry.
+type FingerprintStrategy interface {
+	Fingerprint(e *sentry.Event, errs []error) []string
+}
+
+// FingerprintFunc is a function which implements FingerprintStrategy.
+type FingerprintFunc func(e *sentry.Event, errs []error) []string
+
+// Fingerprint calls f.
+func (f FingerprintFunc) Fingerprint(e *sentry.Event, errs []error) []string {
+	return f(e, errs)
+}
+
+var (
+	// FingerprintFrames uses the filename, function, and line number of all
+	// of the in-app frames in the first exception stacktrace, which is the
+	// glog call site. This is the default strategy.
+	FingerprintFrames FingerprintStrategy = FingerprintFunc(fingerprintFrames)
+
+	// FingerprintFunctions uses the filename and function of all of the
+	// in-app frames in the first exception stacktrace, so that issues are
+	// not split when unrelated lines are added or removed.
+	FingerprintFunctions FingerprintStrategy = FingerprintFunc(fingerprintFunctions)
+
+	// FingerprintTypeAndCallSite uses the type of the first exception, with
+	// any numbers removed, and the innermost in-app function of its
+	// stacktrace. The first exception is the root cause in the order used by
+	// Sentry, and is the glog call site, so the function is the one which
+	// called glog.
+	FingerprintTypeAndCallSite FingerprintStrategy = FingerprintFunc(fingerprintTypeAndCallSite)
+
+	// FingerprintErrorTypes uses the Go type of each error in the chain,
+	// as formatted by %T, such as "*fs.PathError".
+	FingerprintErrorTypes FingerprintStrategy = FingerprintFunc(fingerprintErrorTypes)
+
+	// FingerprintSentryDefault uses Sentry's default grouping. It is used in
+	// a composite strategy to refine the default grouping.
+	FingerprintSentryDefault FingerprintStrategy = FingerprintFunc(func(*sentry.Event, []error) []string {
+		return []string{"{{ default }}"}
+	})
+)
+
+// FingerprintTopFrames is like FingerprintFrames, but only uses the n
+// innermost in-app frames.
+func FingerprintTopFrames(n int) FingerprintStrategy {
+	return FingerprintFunc(func(e *sentry.Event, errs []error) []string {
+		frames := fingerprintFrames(e, errs)
+		if len(frames) > n {
+			frames = frames[len(frames)-n:]
+		}
+		return frames
+	})
+}
+
+// FingerprintComposite concatenates the fingerprints of the strategies,
+// for example to group by error types within Sentry's default grouping:
+//
+//	sentry.FingerprintComposite(sentry.FingerprintSentryDefault, sentry.FingerprintErrorTypes)
+func FingerprintComposite(strategies ...FingerprintStrategy) FingerprintStrategy {
+	return FingerprintFunc(func(e *sentry.Event, errs []error) []string {
+		var r []string
+		for _, s := range strategies {
+			r = append(r, s.Fingerprint(e, errs)...)
+		}
+		return r
+	})
+}
+
+// inAppFrames returns the in-app frames of the first exception stacktrace,
+// outermost first.
+func inAppFrames(e *sentry.Event) []sentry.Frame {
+	if len(e.Exception) == 0 || e.Exception[0].Stacktrace == nil {
+		return nil
+	}
+	var frames []sentry.Frame
+	for _, f := range e.Exception[0].Stacktrace.Frames {
+		if f.InApp {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+func fingerprintFrames(e *sentry.Event, _ []error) []string {
+	var r []string
+	for _, f := range inAppFrames(e) {
+		r = append(r, fmt.Sprintf("%s in %s at line %d", f.Filename, f.Function, f.Lineno))
+	}
+	return r
+}
+
+func fingerprintFunctions(e *sentry.Event, _ []error) []string {
+	var r []string
+	for _, f := range inAppFrames(e) {
+		r = append(r, fmt.Sprintf("%s in %s", f.Filename, f.Function))
+	}
+	return r
+}
+
+var numberRe = regexp.MustCompile(`\b(0x)?[0-9a-fA-F]*[0-9][0-9a-fA-F]*\b`)
+
+func fingerprintTypeAndCallSite(e *sentry.Event, _ []error) []string {
+	if len(e.Exception) == 0 {
+		return nil
+	}
+	// The type and the call site are both taken from the root cause,
+	// which is the exception inAppFrames uses.
+	exceptionType := numberRe.ReplaceAllString(e.Exception[0].Type, "#")
+	r := []string{strings.TrimSpace(exceptionType)}
+	if frames := inAppFrames(e); len(frames) > 0 {
+		f := frames[len(frames)-1]
+		r = append(r, f.Module+"."+f.Function)
+	}
+	return r
+}
+
+func fingerprintErrorTypes(_ *sentry.Event, errs []error) []string {
+	var r []string
+	for _, err := range errs {
+		r = append(r, fmt.Sprintf("%T", err))
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package sentry_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"golang.org/x/xerrors"
+)
+
+// nestedEvent returns an event logged from a function called by the test,
+// so that its stack trace has two in-app frames.
+func nestedEvent(message string, data ...interface{}) glog.Event {
+	return withCallers(glog.Event{Severity: "ERROR", Message: []byte(message), Data: data})
+}
+
+func fingerprint(e glog.Event, options ...sentry.Option) []string {
+	s, _ := sentry.FromGlogEvent(e, true, options...)
+	return s.Fingerprint
+}
+
+func TestFingerprintFrames(t *testing.T) {
+	e := nestedEvent("message")
+	fp := fingerprint(e, sentry.WithFingerprintStrategy(sentry.FingerprintFrames))
+	if assert.Len(t, fp, 2) {
+		assert.Regexp(t, `fingerprint_test.go in TestFingerprintFrames at line \d+$`, fp[0])
+		assert.Regexp(t, `fingerprint_test.go in nestedEvent at line \d+$`, fp[1])
+	}
+
+	// FingerprintFrames is the default strategy
+	assert.Equal(t, fp, fingerprint(e, sentry.WithFingerprinting(true)))
+	assert.Empty(t, fingerprint(e, sentry.WithFingerprinting(false)))
+}
+
+func TestFingerprintFunctions(t *testing.T) {
+	fp := fingerprint(nestedEvent("message"), sentry.WithFingerprintStrategy(sentry.FingerprintFunctions))
+	if assert.Len(t, fp, 2) {
+		assert.True(t, strings.HasSuffix(fp[0], "fingerprint_test.go in TestFingerprintFunctions"), fp[0])
+		assert.True(t, strings.HasSuffix(fp[1], "fingerprint_test.go in nestedEvent"), fp[1])
+	}
+}
+
+func TestFingerprintTopFrames(t *testing.T) {
+	fp := fingerprint(nestedEvent("message"), sentry.WithFingerprintStrategy(sentry.FingerprintTopFrames(1)))
+	if assert.Len(t, fp, 1) {
+		assert.Regexp(t, `in nestedEvent at line \d+$`, fp[0])
+	}
+}
+
+func TestFingerprintTypeAndCallSite(t *testing.T) {
+	strategy := sentry.WithFingerprintStrategy(sentry.FingerprintTypeAndCallSite)
+	fp := fingerprint(nestedEvent("user 1234 not found"), strategy)
+	assert.Equal(t, []string{"user # not found", "github.com/yext/glog-contrib/sentry_test.nestedEvent"}, fp)
+	assert.Equal(t, fp, fingerprint(nestedEvent("user 5678 not found"), strategy))
+
+	// With wrapped errors, the type and the call site are both taken from
+	// the root cause, rather than the top-level exception.
+	e := nestedEvent("lookup 12 failed",
+		glog.FormatStringArg{Format: "lookup %d failed"},
+		glog.ErrorArg{Error: xerrors.New("user 1234 not found")})
+	s, _ := sentry.FromGlogEvent(e, false, strategy)
+	assert.Equal(t, "user 1234 not found", s.Exception[len(s.Exception)-1].Type)
+	assert.Equal(t, []string{"lookup failed", "github.com/yext/glog-contrib/sentry_test.nestedEvent"}, s.Fingerprint)
+}
+
+func TestFingerprintErrorTypes(t *testing.T) {
+	_, err := os.Open("/does/not/exist")
+	e := nestedEvent("message", glog.ErrorArg{Error: xerrors.Errorf("opening: %w", err)})
+
+	fp := fingerprint(e, sentry.WithFingerprintStrategy(sentry.FingerprintErrorTypes))
+	assert.Equal(t, []string{"*xerrors.wrapError", "*fs.PathError", "syscall.Errno"}, fp)
+
+	composite := sentry.FingerprintComposite(sentry.FingerprintSentryDefault, sentry.FingerprintErrorTypes)
+	fp = fingerprint(e, sentry.WithFingerprintStrategy(composite))
+	assert.Equal(t, []string{"{{ default }}", "*xerrors.wrapError", "*fs.PathError", "syscall.Errno"}, fp)
+}
+
+func TestFingerprintByAttribute(t *testing.T) {
+	e := nestedEvent("message", sentry.FingerprintBy(sentry.FingerprintErrorTypes),
+		glog.ErrorArg{Error: fmt.Errorf("plain error")})
+	assert.Equal(t, []string{"*errors.errorString"}, fingerprint(e, sentry.WithFingerprinting(false)))
+	assert.Equal(t, []string{"*errors.errorString"}, fingerprint(e, sentry.WithFingerprintStrategy(sentry.FingerprintFrames)))
+
+	// An explicit fingerprint takes precedence
+	e.Data = append(e.Data, sentry.Fingerprint("explicit"))
+	assert.Equal(t, []string{"explicit"}, fingerprint(e))
+}
@@ -1,7 +1,6 @@
 package sentry
 
 import (
-	"regexp"
 	"strings"
 
 	"github.com/yext/glog-contrib/stacktrace"
@@ -10,12 +9,6 @@ import (
 	"golang.org/x/xerrors"
 )
 
-var formatStringRe *regexp.Regexp
-
-func init() {
-	formatStringRe = regexp.MustCompile(`%#?\+?\w+ ?`)
-}
-
 // headline returns a good Headline for this error.
 // Ideally, it returns a succinct summary that best conveys the error.
 // Most likely, that's something close to the root cause, but that may
@@ -78,18 +71,6 @@ func addExceptionSource(value string, trace *sentry.Stacktrace) string {
 	}
 }
 
-// cleanupFormatString takes in a message with printf formatter characters
-// (e.g. "error performing action %s: %s") and strips the percent characters,
-// also cleaning up whitespace and trailing colons.
-func cleanupFormatString(format string) string {
-	format = formatStringRe.ReplaceAllString(format, "")
-	format = strings.TrimSpace(format)
-	format = strings.TrimSuffix(format, ":")
-	format = strings.TrimSpace(format)
-
-	return format
-}
-
 // prependMessage prepends the given possiblePrefix to an
 // existing fullMsg. If fullMsg starts with possiblePrefix
 // then the prefix is removed. Otherwise the possiblePrefix
@@ -0,0 +1,47 @@
+package sentry_test
+
+import (
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+)
+
+func TestCaptureErrorsEndToEnd(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		sentry.CaptureErrors("example",
+			[]string{server.DSN("1"), server.DSN("2")},
+			sentrygo.ClientOptions{},
+			comm)
+		close(done)
+	}()
+
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("primary message")})
+	comm <- withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("secondary message"),
+		Data:     []interface{}{sentry.AltDsn(server.DSN("2"))},
+	})
+
+	server.RequireEvents(t, 2)
+	close(comm)
+	<-done
+
+	primary := server.ProjectEvents("1")
+	if assert.Len(t, primary, 1) {
+		assert.Equal(t, "primary message", primary[0].Message)
+		assert.Equal(t, sentrygo.LevelError, primary[0].Level)
+	}
+	secondary := server.ProjectEvents("2")
+	if assert.Len(t, secondary, 1) {
+		assert.Equal(t, "secondary message", secondary[0].Message)
+	}
+}
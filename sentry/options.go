@@ -0,0 +1,160 @@
+package sentry
+
+import (
+	"os"
+
+	"github.com/yext/glog-contrib/stacktrace"
+)
+
+// Options configures how glog events are converted into Sentry events.
+// Some defaults are taken from the command line flags of this package, so
+// that Options only need to be passed to override them.
+type Options struct {
+	// Fingerprinting groups issues by the fingerprint built by the
+	// FingerprintStrategy, rather than the error message.
+	// Defaults to the -sentryFingerprinting flag.
+	Fingerprinting bool
+	// FingerprintStrategy builds fingerprints when Fingerprinting is
+	// enabled. Defaults to FingerprintFrames.
+	FingerprintStrategy FingerprintStrategy
+	// Debug enables debug mode in Sentry clients.
+	// Defaults to the -sentryDebug flag.
+	Debug bool
+	// ThreadDumps attaches the stacks of all goroutines to FATAL events
+	// sent by Fatal and to recovered panics, taken when they occur.
+	// Defaults to the -sentryThreadDumps flag.
+	ThreadDumps bool
+	// Dedup deduplicates and merges exceptions and their stack traces.
+	// Defaults to true, or the exceptionDedup argument of FromGlogEvent.
+	Dedup bool
+	// DetectRelease sets the release and distribution of clients with
+	// DetectRelease, if they are not set in the client options, and
+	// attaches the build information to events. Defaults to true.
+	DetectRelease bool
+	// BuildDependencies adds the versions of the modules the binary depends
+	// on to the build information attached with DetectRelease.
+	// Defaults to false.
+	BuildDependencies bool
+	// Enrichers add information about the process and host to events.
+	// Defaults to DefaultEnrichers.
+	Enrichers []Enricher
+	// Serializer converts the values of data maps attached to glog events
+	// so that they can be sent. Defaults to DefaultSerializer.
+	Serializer Serializer
+	// TagKeys lists the keys of data maps which are set as tags, rather
+	// than being attached to events as extra data.
+	TagKeys []string
+	// Hostname is the server name set on events. Defaults to the short
+	// hostname of the machine.
+	Hostname string
+	// Logger is the logger name set on events. Defaults to the path of the
+	// running binary, relative to GOPATH.
+	Logger string
+	// MaxErrorDepth is the maximum number of wrapped errors processed.
+	// Defaults to 10.
+	MaxErrorDepth int
+	// Ownership sets the owner and team tags of events from the innermost
+	// in-app frame where the error originated, unless the routing
+	// configuration has its own.
+	Ownership *Ownership
+}
+
+// Option overrides one of the Options.
+type Option func(*Options)
+
+// WithFingerprinting sets Options.Fingerprinting.
+func WithFingerprinting(enabled bool) Option {
+	return func(o *Options) { o.Fingerprinting = enabled }
+}
+
+// WithFingerprintStrategy sets Options.FingerprintStrategy, and enables
+// fingerprinting.
+func WithFingerprintStrategy(strategy FingerprintStrategy) Option {
+	return func(o *Options) {
+		o.Fingerprinting = true
+		o.FingerprintStrategy = strategy
+	}
+}
+
+// WithDebug sets Options.Debug.
+func WithDebug(enabled bool) Option {
+	return func(o *Options) { o.Debug = enabled }
+}
+
+// WithThreadDumps sets Options.ThreadDumps.
+func WithThreadDumps(enabled bool) Option {
+	return func(o *Options) { o.ThreadDumps = enabled }
+}
+
+// WithDedup sets Options.Dedup.
+func WithDedup(enabled bool) Option {
+	return func(o *Options) { o.Dedup = enabled }
+}
+
+// WithReleaseDetection sets Options.DetectRelease.
+func WithReleaseDetection(enabled bool) Option {
+	return func(o *Options) { o.DetectRelease = enabled }
+}
+
+// WithBuildDependencies sets Options.BuildDependencies.
+func WithBuildDependencies(enabled bool) Option {
+	return func(o *Options) { o.BuildDependencies = enabled }
+}
+
+// WithEnrichers sets Options.Enrichers, replacing the defaults. Pass no
+// enrichers to disable them, or include DefaultEnrichers() to extend them.
+func WithEnrichers(enrichers ...Enricher) Option {
+	return func(o *Options) { o.Enrichers = enrichers }
+}
+
+// WithSerializer sets Options.Serializer.
+func WithSerializer(serializer Serializer) Option {
+	return func(o *Options) { o.Serializer = serializer }
+}
+
+// WithTagKeys adds keys to Options.TagKeys.
+func WithTagKeys(keys ...string) Option {
+	return func(o *Options) { o.TagKeys = append(o.TagKeys, keys...) }
+}
+
+// WithHostname sets Options.Hostname.
+func WithHostname(hostname string) Option {
+	return func(o *Options) { o.Hostname = hostname }
+}
+
+// WithLogger sets Options.Logger.
+func WithLogger(logger string) Option {
+	return func(o *Options) { o.Logger = logger }
+}
+
+// WithMaxErrorDepth sets Options.MaxErrorDepth.
+func WithMaxErrorDepth(depth int) Option {
+	return func(o *Options) { o.MaxErrorDepth = depth }
+}
+
+// WithOwnership sets Options.Ownership.
+func WithOwnership(ownership *Ownership) Option {
+	return func(o *Options) { o.Ownership = ownership }
+}
+
+// buildOptions applies the options to the defaults. The flags are read
+// when it is called, rather than at init, so that they have been parsed.
+func buildOptions(options []Option) Options {
+	o := Options{
+		Fingerprinting:      sentryFingerprinting,
+		FingerprintStrategy: FingerprintFrames,
+		Debug:               sentryDebug,
+		ThreadDumps:         sentryThreadDumps,
+		Dedup:               true,
+		DetectRelease:       true,
+		Enrichers:           DefaultEnrichers(),
+		Serializer:          DefaultSerializer,
+		Hostname:            hostname,
+		Logger:              stacktrace.GopathRelativeFile(os.Args[0]),
+		MaxErrorDepth:       maxErrorDepth,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
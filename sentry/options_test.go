@@ -0,0 +1,92 @@
+package sentry_test
+
+import (
+	"flag"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+	"golang.org/x/xerrors"
+)
+
+func TestFromGlogEventFingerprinting(t *testing.T) {
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		s, _ := sentry.FromGlogEvent(e, true, sentry.WithFingerprinting(true))
+		assert.NotEmpty(t, s.Fingerprint)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		s, _ := sentry.FromGlogEvent(e, true, sentry.WithFingerprinting(false))
+		assert.Empty(t, s.Fingerprint)
+	})
+}
+
+func TestFromGlogEventOptions(t *testing.T) {
+	err := xerrors.Errorf("outer: %w", xerrors.Errorf("middle: %w", xerrors.New("inner")))
+	e := withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("message"),
+		Data:     []interface{}{glog.ErrorArg{Error: err}},
+	})
+
+	s, _ := sentry.FromGlogEvent(e, false,
+		sentry.WithHostname("example-host"),
+		sentry.WithLogger("example-logger"))
+	assert.Equal(t, "example-host", s.ServerName)
+	assert.Equal(t, "example-logger", s.Logger)
+	assert.Len(t, s.Exception, 4, "the glog call site and three errors")
+
+	s, _ = sentry.FromGlogEvent(e, false, sentry.WithMaxErrorDepth(1))
+	assert.Len(t, s.Exception, 2, "the glog call site and the outer error")
+
+	// Options are applied after the exceptionDedup argument
+	deduped, _ := sentry.FromGlogEvent(e, false, sentry.WithDedup(true))
+	expected, _ := sentry.FromGlogEvent(e, true)
+	assert.Equal(t, len(expected.Exception), len(deduped.Exception))
+}
+
+func TestCaptureErrorsOptions(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		sentry.CaptureErrors("example", []string{server.DSN("1")}, sentrygo.ClientOptions{}, comm,
+			sentry.WithHostname("example-host"),
+			sentry.WithFingerprinting(true))
+		close(done)
+	}()
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	close(comm)
+	<-done
+
+	events := server.RequireEvents(t, 1)
+	assert.Equal(t, "example-host", events[0].ServerName)
+	assert.NotEmpty(t, events[0].Fingerprint)
+}
+
+func TestRegisterFlags(t *testing.T) {
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	s, _ := sentry.FromGlogEvent(e, true)
+	assert.Empty(t, s.Fingerprint)
+	assert.NotNil(t, flag.Lookup("sentryFingerprinting"), "flags are registered on the command line")
+	assert.NotPanics(t, func() { sentry.RegisterFlags(flag.CommandLine) }, "flags are only registered once")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	sentry.RegisterFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"-sentryFingerprinting"}))
+	defer fs.Set("sentryFingerprinting", "false")
+
+	s, _ = sentry.FromGlogEvent(e, true)
+	assert.NotEmpty(t, s.Fingerprint)
+	assert.Equal(t, "true", flag.Lookup("sentryFingerprinting").Value.String(), "both flag sets share the value")
+	s, _ = sentry.FromGlogEvent(e, true, sentry.WithFingerprinting(false))
+	assert.Empty(t, s.Fingerprint, "options override the flags")
+}
@@ -0,0 +1,189 @@
+package sentry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// The tags set on events by an Ownership.
+const (
+	// OwnerTag is set to the first owner of the code, such as "@org/team"
+	// or "user@example.com".
+	OwnerTag = "owner"
+	// TeamTag is set to the name of the first team which owns the code,
+	// such as "team" for "@org/team".
+	TeamTag = "team"
+)
+
+// Ownership resolves the owners of source files from a CODEOWNERS file.
+// Patterns follow the GitHub CODEOWNERS format, and the last matching
+// pattern takes precedence.
+type Ownership struct {
+	// root is removed from filenames before they are matched.
+	root  string
+	rules []ownershipRule
+}
+
+type ownershipRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// LoadCodeowners reads a CODEOWNERS file. See ParseCodeowners.
+func LoadCodeowners(path, root string) (*Ownership, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCodeowners(f, root)
+}
+
+// ParseCodeowners parses a file in the CODEOWNERS format, such as:
+//
+//	*.proto           @org/api
+//	/services/search/ @org/search search-oncall@example.com
+//
+// Frame filenames are relative to GOPATH, such as
+// "github.com/org/repo/services/search/index.go", so root is the prefix to
+// remove to make them relative to the repository, such as
+// "github.com/org/repo/". If root is empty, patterns anchored to the
+// repository root instead match at any directory.
+func ParseCodeowners(r io.Reader, root string) (*Ownership, error) {
+	o := &Ownership{root: strings.TrimSuffix(root, "/")}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		var owners []string
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			owners = append(owners, owner)
+		}
+		pattern, err := codeownersPattern(fields[0], o.root != "")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		o.rules = append(o.rules, ownershipRule{pattern: pattern, owners: owners})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// codeownersPattern converts a gitignore-style pattern into a regular
+// expression matching the paths it applies to, including files within
+// matching directories.
+func codeownersPattern(pattern string, rooted bool) (*regexp.Regexp, error) {
+	// A slash at the start or in the middle anchors the pattern to the
+	// repository root, otherwise it matches at any directory.
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var b strings.Builder
+	if anchored && rooted {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	switch {
+	case dir:
+		b.WriteString("/")
+	case strings.HasSuffix(pattern, "/*") && !strings.HasSuffix(pattern, "**/*"):
+		// Only files directly within the directory are matched.
+		b.WriteString("$")
+	default:
+		b.WriteString("(/|$)")
+	}
+	return regexp.Compile(b.String())
+}
+
+// Owners returns the owners of the file, or nil if it has none.
+func (o *Ownership) Owners(filename string) []string {
+	owners, _ := o.match(filename)
+	return owners
+}
+
+// match returns the owners of the last rule matching the file, and whether
+// any rule matched. A matching rule without owners marks the file as
+// unowned.
+func (o *Ownership) match(filename string) ([]string, bool) {
+	if o.root != "" {
+		if !strings.HasPrefix(filename, o.root+"/") {
+			return nil, false
+		}
+		filename = strings.TrimPrefix(filename, o.root+"/")
+	}
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].pattern.MatchString(filename) {
+			return o.rules[i].owners, true
+		}
+	}
+	return nil, false
+}
+
+// setTags sets the owner and team tags of the event from the innermost
+// in-app frame of the stack trace which matches a rule. If that rule marks
+// the frame as unowned, no tags are set. Tags which are already set, such
+// as with the Tag attribute, are kept.
+func (o *Ownership) setTags(e *sentry.Event, trace *sentry.Stacktrace) {
+	if trace == nil {
+		return
+	}
+	for i := len(trace.Frames) - 1; i >= 0; i-- {
+		f := trace.Frames[i]
+		if !f.InApp {
+			continue
+		}
+		owners, ok := o.match(f.Filename)
+		if !ok {
+			continue
+		}
+		if len(owners) == 0 {
+			return
+		}
+
+		if _, ok := e.Tags[OwnerTag]; !ok {
+			e.Tags[OwnerTag] = owners[0]
+		}
+		for _, owner := range owners {
+			if slash := strings.Index(owner, "/"); strings.HasPrefix(owner, "@") && slash != -1 {
+				if _, ok := e.Tags[TeamTag]; !ok {
+					e.Tags[TeamTag] = owner[slash+1:]
+				}
+				break
+			}
+		}
+		return
+	}
+}
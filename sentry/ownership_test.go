@@ -0,0 +1,129 @@
+package sentry_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+)
+
+const codeowners = `
+# Default owners
+*                    @org/platform
+
+*.proto              @org/api
+/services/search/    @org/search search-oncall@example.com
+/services/ads/*      @org/ads
+docs/**/guides       writer@example.com
+/services/unowned/
+`
+
+func TestCodeownersPatterns(t *testing.T) {
+	o, err := sentry.ParseCodeowners(strings.NewReader(codeowners), "github.com/org/repo")
+	assert.NoError(t, err)
+
+	for filename, expected := range map[string][]string{
+		"github.com/org/repo/main.go":                           {"@org/platform"},
+		"github.com/org/repo/services/api/v1/types.proto":       {"@org/api"},
+		"github.com/org/repo/services/search/index.go":          {"@org/search", "search-oncall@example.com"},
+		"github.com/org/repo/services/search/rank/rank.go":      {"@org/search", "search-oncall@example.com"},
+		"github.com/org/repo/lib/services/search/index.go":      {"@org/platform"},
+		"github.com/org/repo/services/ads/serve.go":             {"@org/ads"},
+		"github.com/org/repo/services/ads/bid/bid.go":           {"@org/platform"},
+		"github.com/org/repo/docs/guides/setup.md":              {"writer@example.com"},
+		"github.com/org/repo/docs/v2/guides/setup.md":           {"writer@example.com"},
+		"github.com/org/repo/services/unowned/code.go":          nil,
+		"github.com/other/repo/services/search/index.go":        nil,
+		"github.com/org/repo/services/search/testdata/a.proto":  {"@org/search", "search-oncall@example.com"},
+		"github.com/org/repo/services/searchengine/index.go":    {"@org/platform"},
+		"github.com/org/repo/services/search/index_test.go.bak": {"@org/search", "search-oncall@example.com"},
+	} {
+		assert.Equal(t, expected, o.Owners(filename), filename)
+	}
+}
+
+func TestCodeownersWithoutRoot(t *testing.T) {
+	o, err := sentry.ParseCodeowners(strings.NewReader(codeowners), "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"@org/search", "search-oncall@example.com"},
+		o.Owners("/home/user/repo/services/search/index.go"))
+	assert.Equal(t, []string{"@org/platform"}, o.Owners("services/searchengine/index.go"))
+}
+
+func TestRoutingCodeowners(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	o, err := sentry.ParseCodeowners(strings.NewReader(`
+*                        @org/platform
+/sentry/*_test.go        @org/observability
+`), "")
+	assert.NoError(t, err)
+	routing := sentry.Routing{
+		Rules:     []sentry.RoutingRule{{Tags: map[string]string{sentry.TeamTag: "observability"}, DSNs: []string{server.DSN("a")}}},
+		Ownership: o,
+	}
+
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	assert.Equal(t, []string{"a"}, routeEvent(server, routing, e))
+	events := server.Events()
+	assert.Equal(t, "@org/observability", events[0].Tags[sentry.OwnerTag])
+	assert.Equal(t, "observability", events[0].Tags[sentry.TeamTag])
+
+	// Tags set on the event take precedence
+	e.Data = []interface{}{sentry.Tag(sentry.TeamTag, "other")}
+	assert.Equal(t, []string{"primary"}, routeEvent(server, routing, e))
+	events = server.Events()
+	assert.Equal(t, "@org/observability", events[0].Tags[sentry.OwnerTag])
+	assert.Equal(t, "other", events[0].Tags[sentry.TeamTag])
+}
+
+func TestOwnershipWithoutRouting(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	o, err := sentry.ParseCodeowners(strings.NewReader(`/sentry/*_test.go @org/observability`), "")
+	assert.NoError(t, err)
+
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	assert.Equal(t, []string{"primary"}, routeEvent(server, sentry.Routing{}, e, sentry.WithOwnership(o)))
+	events := server.Events()
+	assert.Equal(t, "@org/observability", events[0].Tags[sentry.OwnerTag])
+	assert.Equal(t, "observability", events[0].Tags[sentry.TeamTag])
+
+	// Events sent to a target DSN are also tagged
+	routing := sentry.Routing{Ownership: o, Default: []string{server.DSN("a"), server.DSN("b")}}
+	e.Data = []interface{}{sentry.AltDsn(server.DSN("b"))}
+	assert.Equal(t, []string{"b"}, routeEvent(server, routing, e))
+	events = server.Events()
+	assert.Equal(t, "observability", events[0].Tags[sentry.TeamTag])
+}
+
+func TestOwnershipUnowned(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	o, err := sentry.ParseCodeowners(strings.NewReader(`
+/sentry/*_test.go           @org/observability
+/sentry/fingerprint_test.go
+`), "")
+	assert.NoError(t, err)
+
+	// The innermost frame, in fingerprint_test.go, is explicitly unowned, so
+	// the owners of the outer frame in this file are not used.
+	routeEvent(server, sentry.Routing{}, nestedEvent("message"), sentry.WithOwnership(o))
+	events := server.Events()
+	assert.NotContains(t, events[0].Tags, sentry.OwnerTag)
+	assert.NotContains(t, events[0].Tags, sentry.TeamTag)
+
+	// Frames which match no rule are skipped
+	o, err = sentry.ParseCodeowners(strings.NewReader(`/sentry/ownership_test.go @org/observability`), "")
+	assert.NoError(t, err)
+	routeEvent(server, sentry.Routing{}, nestedEvent("message"), sentry.WithOwnership(o))
+	events = server.Events()
+	assert.Equal(t, "observability", events[0].Tags[sentry.TeamTag])
+}
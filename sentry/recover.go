@@ -0,0 +1,148 @@
+package sentry
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+
+	"github.com/yext/glog"
+)
+
+// Helpers which recover from panics and report them through the same
+// processing as glog errors, so that they are formatted, deduplicated and
+// routed like any other Sentry event from this package.
+
+// PanicError is the error reported for a recovered panic. Its stack trace
+// starts at the site of the panic, rather than where it was recovered.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	stack []uintptr
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns the panic value, if it is an error.
+func (p *PanicError) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// StackTrace returns the program counters of the panicking goroutine,
+// starting at the site of the panic.
+func (p *PanicError) StackTrace() []uintptr {
+	return p.stack
+}
+
+// Recover reports a panic in the calling goroutine and stops it from
+// unwinding further. It must be deferred directly:
+//
+//	defer sentry.Recover()
+func Recover() {
+	if r := recover(); r != nil {
+		reportPanic(r, false)
+	}
+}
+
+// RecoverAndRepanic reports a panic in the calling goroutine, waits for it
+// to be sent, and then continues panicking with the same value. It must be
+// deferred directly:
+//
+//	defer sentry.RecoverAndRepanic()
+func RecoverAndRepanic() {
+	if r := recover(); r != nil {
+		reportPanic(r, true)
+		panic(r)
+	}
+}
+
+// Go runs f in a new goroutine. If f panics, the panic is reported and the
+// goroutine exits without crashing the process.
+func Go(f func()) {
+	go func() {
+		defer Recover()
+		f()
+	}()
+}
+
+// RecoverHandler wraps an http.Handler, reporting any panic while serving a
+// request along with the request itself, and responding with a 500 status.
+// Panics with http.ErrAbortHandler are passed through unreported, since they
+// are used to abort a response on purpose.
+func RecoverHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				reportPanic(rec, false, r)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// reportPanic must be called from the deferred function which recovered the
+// panic, since it looks up the panic site from the current stack.
+//
+// The panic is logged through glog at the ERROR level. If CaptureErrors is
+// running, the event is also sent to Sentry directly (and skipped when it
+// arrives over the glog channel), so that it can be flushed before the
+// process exits.
+func reportPanic(value interface{}, flush bool, data ...interface{}) {
+	stack, depth := panicStack()
+	err := &PanicError{Value: value, stack: stack}
+
+	data = append(data, glog.ErrorArg{Error: err}, mechanismPanic)
+	if captureDirect(glog.Event{
+		Severity:   "ERROR",
+		Message:    []byte(err.Error()),
+		Data:       append(data[:len(data):len(data)], dumpThreads{}),
+		StackTrace: stack,
+	}, flush) {
+		data = append(data, captured{})
+	}
+
+	args := []interface{}{err}
+	for _, d := range data {
+		args = append(args, glog.Data(d))
+	}
+	glog.ErrorWithDepth(depth, args...)
+}
+
+// panicStack returns the program counters of the panicking goroutine,
+// starting at the site of the panic, along with the number of frames
+// between the caller of panicStack and the panic site. If the goroutine
+// is not panicking, the stack of the caller is returned.
+func panicStack() ([]uintptr, int) {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	depth := 0
+	inPanic := false
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if inPanic && !strings.HasPrefix(frame.Function, "runtime.") {
+			break
+		}
+		if frame.Function == "runtime.gopanic" {
+			inPanic = true
+		}
+		if !more {
+			return pcs, 0
+		}
+		depth++
+	}
+
+	stack := make([]uintptr, 64)
+	return stack[:runtime.Callers(2+depth, stack)], depth
+}
@@ -0,0 +1,109 @@
+package sentry_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+)
+
+// nextError returns the next ERROR event received on the glog channel.
+func nextError(t *testing.T, ch <-chan glog.Event) glog.Event {
+	for {
+		select {
+		case e := <-ch:
+			if e.Severity == "ERROR" {
+				return e
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for glog event")
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	methodName := "TestRecover.func1" // this should stay in sync with the name of the method
+
+	ch := registerBackend()
+	var panicLine int
+	func() {
+		defer sentry.Recover()
+		panicLine = 1 + currentLine() // this should point to the next line
+		panic("test panic")
+	}()
+
+	e, _ := sentry.FromGlogEvent(nextError(t, ch), true)
+	assert.Equal(t, "panic: test panic", e.Message)
+	assert.Len(t, e.Exception, 1, "one exception")
+
+	ex := e.Exception[0]
+	assert.Equal(t, sentry.MechanismPanic, ex.Mechanism.Type)
+	assert.False(t, *ex.Mechanism.Handled, "panics are unhandled")
+	assert.Equal(t, "panic", ex.Type)
+	assert.True(t, strings.HasPrefix(ex.Value, "test panic"), ex.Value)
+	assert.NotNil(t, ex.Stacktrace)
+	fr := ex.Stacktrace.Frames[len(ex.Stacktrace.Frames)-1]
+	assert.Equal(t, methodName, fr.Function, "innermost frame is the panic site")
+	assert.Equal(t, panicLine, fr.Lineno, "line number matches the panic")
+}
+
+func TestRecoverError(t *testing.T) {
+	ch := registerBackend()
+	func() {
+		defer sentry.Recover()
+		panic(errors.New("test error"))
+	}()
+
+	ge := nextError(t, ch)
+	var err *sentry.PanicError
+	for _, d := range ge.Data {
+		if arg, ok := d.(glog.ErrorArg); ok {
+			assert.True(t, errors.As(arg.Error, &err))
+		}
+	}
+	assert.NotNil(t, err)
+	assert.Equal(t, "test error", err.Unwrap().Error())
+}
+
+func TestRecoverAndRepanic(t *testing.T) {
+	ch := registerBackend()
+	assert.PanicsWithValue(t, "test panic", func() {
+		defer sentry.RecoverAndRepanic()
+		panic("test panic")
+	})
+
+	e, _ := sentry.FromGlogEvent(nextError(t, ch), true)
+	assert.Equal(t, "panic: test panic", e.Message)
+}
+
+func TestGo(t *testing.T) {
+	ch := registerBackend()
+	sentry.Go(func() {
+		panic("test panic")
+	})
+
+	e, _ := sentry.FromGlogEvent(nextError(t, ch), true)
+	assert.Equal(t, "panic: test panic", e.Message)
+}
+
+func TestRecoverHandler(t *testing.T) {
+	ch := registerBackend()
+	h := sentry.RecoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("test panic")
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com/path?q=1", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	e, _ := sentry.FromGlogEvent(nextError(t, ch), true)
+	assert.Equal(t, "panic: test panic", e.Message)
+	assert.NotNil(t, e.Request)
+	assert.Equal(t, "http://example.com/path?q=1", e.Request.URL)
+}
@@ -0,0 +1,126 @@
+package sentry
+
+import (
+	"os"
+	"runtime/debug"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// The name of the Sentry context containing the build information.
+const buildContext = "build"
+
+// Environment variables which are checked, in order, for the release.
+// Most are set by CI or hosting providers to the commit being built.
+var releaseEnvVars = []string{
+	"SENTRY_RELEASE",
+	"GIT_COMMIT",
+	"GIT_SHA",
+	"COMMIT_SHA",
+	"SOURCE_VERSION",
+	"GITHUB_SHA",
+	"CI_COMMIT_SHA",
+	"CIRCLE_SHA1",
+	"BUILD_VCS_NUMBER",
+	"HEROKU_SLUG_COMMIT",
+	"CODEBUILD_RESOLVED_SOURCE_VERSION",
+}
+
+// Environment variables which are checked, in order, for the distribution.
+var distEnvVars = []string{
+	"SENTRY_DIST",
+	"BUILD_NUMBER",
+	"BUILD_ID",
+}
+
+// BuildRelease describes the release of a binary.
+type BuildRelease struct {
+	// Release is the version of the main module, or the VCS revision it was
+	// built from if the version is not known.
+	Release string
+	// Dist identifies the build of the release, if known.
+	Dist string
+	// Context contains the Go version, build settings and the version of
+	// the main module, to be attached to events as a Sentry context.
+	Context sentry.Context
+	// Dependencies maps the paths of the modules the binary depends on to
+	// their versions. They are only attached to events, in Context, with
+	// Options.BuildDependencies.
+	Dependencies map[string]string
+}
+
+// context returns the context attached to events, which includes the
+// dependencies if they are enabled.
+func (r BuildRelease) context(dependencies bool) sentry.Context {
+	if !dependencies || r.Context == nil {
+		return r.Context
+	}
+	c := make(sentry.Context, len(r.Context)+1)
+	for k, v := range r.Context {
+		c[k] = v
+	}
+	c["dependencies"] = r.Dependencies
+	return c
+}
+
+// DetectRelease detects the release of the running binary. Environment
+// variables such as SENTRY_RELEASE, SENTRY_DIST, GIT_COMMIT and GITHUB_SHA
+// take precedence over the build information embedded by the Go toolchain.
+func DetectRelease() BuildRelease {
+	info, _ := debug.ReadBuildInfo()
+	r := ReleaseFromBuildInfo(info)
+	for _, name := range releaseEnvVars {
+		if v := os.Getenv(name); v != "" {
+			r.Release = v
+			break
+		}
+	}
+	for _, name := range distEnvVars {
+		if v := os.Getenv(name); v != "" {
+			r.Dist = v
+			break
+		}
+	}
+	return r
+}
+
+// ReleaseFromBuildInfo builds the release from the information embedded in
+// a binary by the Go toolchain. VCS information is only embedded when the
+// binary is built from a repository with "go build".
+func ReleaseFromBuildInfo(info *debug.BuildInfo) BuildRelease {
+	var r BuildRelease
+	if info == nil {
+		return r
+	}
+
+	settings := map[string]string{}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		r.Release = info.Main.Path + "@" + v
+	} else if rev := settings["vcs.revision"]; rev != "" {
+		r.Release = rev
+	}
+
+	r.Dependencies = map[string]string{}
+	for _, d := range info.Deps {
+		version := d.Version
+		if d.Replace != nil {
+			version += " => " + d.Replace.Path + "@" + d.Replace.Version
+		}
+		r.Dependencies[d.Path] = version
+	}
+
+	r.Context = sentry.Context{
+		"go_version":   info.GoVersion,
+		"path":         info.Path,
+		"main_module":  info.Main.Path,
+		"version":      info.Main.Version,
+		"vcs_revision": settings["vcs.revision"],
+		"vcs_time":     settings["vcs.time"],
+		"vcs_modified": settings["vcs.modified"] == "true",
+		"settings":     settings,
+	}
+	return r
+}
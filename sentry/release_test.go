@@ -0,0 +1,139 @@
+package sentry_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+)
+
+func TestReleaseFromBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.22.0",
+		Path:      "github.com/org/service/cmd/server",
+		Main:      debug.Module{Path: "github.com/org/service", Version: "v1.2.3"},
+		Deps: []*debug.Module{
+			{Path: "github.com/org/lib", Version: "v0.4.0"},
+			{Path: "github.com/org/fork", Version: "v1.0.0", Replace: &debug.Module{Path: "../fork", Version: ""}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "CGO_ENABLED", Value: "0"},
+			{Key: "vcs.revision", Value: "4f72d7c0ffee"},
+			{Key: "vcs.time", Value: "2024-01-02T15:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	r := sentry.ReleaseFromBuildInfo(info)
+	assert.Equal(t, "github.com/org/service@v1.2.3", r.Release)
+	assert.Empty(t, r.Dist)
+	assert.Equal(t, "go1.22.0", r.Context["go_version"])
+	assert.Equal(t, "4f72d7c0ffee", r.Context["vcs_revision"])
+	assert.Equal(t, "2024-01-02T15:04:05Z", r.Context["vcs_time"])
+	assert.Equal(t, true, r.Context["vcs_modified"])
+	assert.Equal(t, "0", r.Context["settings"].(map[string]string)["CGO_ENABLED"])
+	assert.Equal(t, map[string]string{
+		"github.com/org/lib":  "v0.4.0",
+		"github.com/org/fork": "v1.0.0 => ../fork@",
+	}, r.Dependencies)
+	assert.NotContains(t, r.Context, "dependencies", "dependencies are only attached if enabled")
+
+	// Without a module version, the VCS revision is used
+	info.Main.Version = "(devel)"
+	assert.Equal(t, "4f72d7c0ffee", sentry.ReleaseFromBuildInfo(info).Release)
+
+	assert.Empty(t, sentry.ReleaseFromBuildInfo(nil).Release)
+}
+
+func TestDetectReleaseFromEnv(t *testing.T) {
+	t.Setenv("SENTRY_RELEASE", "")
+	t.Setenv("SENTRY_DIST", "")
+	t.Setenv("GIT_COMMIT", "abc123")
+	t.Setenv("BUILD_NUMBER", "42")
+
+	r := sentry.DetectRelease()
+	assert.Equal(t, "abc123", r.Release)
+	assert.Equal(t, "42", r.Dist)
+
+	t.Setenv("SENTRY_RELEASE", "service@2.0.0")
+	assert.Equal(t, "service@2.0.0", sentry.DetectRelease().Release)
+}
+
+func TestCaptureErrorsRelease(t *testing.T) {
+	t.Setenv("SENTRY_RELEASE", "service@2.0.0")
+	t.Setenv("SENTRY_DIST", "7")
+
+	capture := func(opts sentrygo.ClientOptions, options ...sentry.Option) sentrytest.Event {
+		server := sentrytest.NewServer()
+		defer server.Close()
+
+		comm := make(chan glog.Event)
+		done := make(chan struct{})
+		go func() {
+			sentry.CaptureErrors("example", []string{server.DSN("1")}, opts, comm, options...)
+			close(done)
+		}()
+		comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+		close(comm)
+		<-done
+		return server.RequireEvents(t, 1)[0]
+	}
+
+	e := capture(sentrygo.ClientOptions{})
+	assert.Equal(t, "service@2.0.0", e.Release)
+	assert.Equal(t, "7", e.Dist)
+	if assert.Contains(t, e.Contexts, "build") {
+		assert.NotEmpty(t, e.Contexts["build"]["go_version"])
+		assert.NotContains(t, e.Contexts["build"], "dependencies")
+	}
+
+	// Releases set explicitly take precedence
+	e = capture(sentrygo.ClientOptions{Release: "explicit@1.0.0"})
+	assert.Equal(t, "explicit@1.0.0", e.Release)
+	assert.Equal(t, "7", e.Dist)
+
+	e = capture(sentrygo.ClientOptions{}, sentry.WithReleaseDetection(false))
+	assert.NotContains(t, e.Contexts, "build")
+
+	e = capture(sentrygo.ClientOptions{}, sentry.WithBuildDependencies(true))
+	if assert.Contains(t, e.Contexts, "build") {
+		assert.Contains(t, e.Contexts["build"], "dependencies")
+	}
+}
+
+func TestReleaseDetectedByDefault(t *testing.T) {
+	for _, name := range []string{"SENTRY_RELEASE", "GIT_COMMIT", "GIT_SHA", "COMMIT_SHA", "SOURCE_VERSION"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GITHUB_SHA", "4f72d7c0ffee")
+
+	capture := func(cfg sentry.Config) sentrytest.Event {
+		server := sentrytest.NewServer()
+		defer server.Close()
+
+		cfg.DSNs = []string{server.DSN("1")}
+		b, err := sentry.NewBackend(cfg, sentrygo.ClientOptions{})
+		assert.NoError(t, err)
+		comm := make(chan glog.Event, 1)
+		comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+		close(comm)
+		b.Run(comm)
+		return server.RequireEvents(t, 1)[0]
+	}
+
+	// Without any configuration, the detected release is used
+	e := capture(sentry.Config{})
+	assert.Equal(t, "4f72d7c0ffee", e.Release)
+	assert.Contains(t, e.Contexts, "build")
+
+	// An explicit release takes precedence
+	e = capture(sentry.Config{Release: "explicit@1.0.0"})
+	assert.Equal(t, "explicit@1.0.0", e.Release)
+	t.Setenv("SENTRY_RELEASE", "service@2.0.0")
+	e = capture(sentry.Config{})
+	assert.Equal(t, "service@2.0.0", e.Release)
+}
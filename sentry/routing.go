@@ -0,0 +1,235 @@
+package sentry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/stacktrace"
+)
+
+// Routing configures which DSNs events are sent to, based on where and how
+// they were logged. It can be decoded from JSON or YAML, for example:
+//
+//	rules:
+//	  - package: github.com/yext/platform
+//	    dsns: [https://platformDsn]
+//	  - errorType: "*net.OpError"
+//	    severity: FATAL
+//	    dsns: [https://networkDsn, https://oncallDsn]
+//	default: [https://productDsn]
+type Routing struct {
+	// Rules are evaluated in order, and the event is sent to the DSNs of
+	// the first rule which matches it.
+	Rules []RoutingRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// Default is the DSNs used for events which match no rule. If empty,
+	// the first DSN passed to CaptureErrors is used.
+	Default []string `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// Codeowners is the path of a CODEOWNERS file, used to set the owner
+	// and team tags of events, which rules can then match to select a DSN
+	// for each team.
+	// The tags are set on all events, including those sent to a target DSN,
+	// and take precedence over Options.Ownership.
+	Codeowners string `json:"codeowners,omitempty" yaml:"codeowners,omitempty"`
+	// CodeownersRoot is the prefix removed from frame filenames before
+	// matching them against the CODEOWNERS file. See ParseCodeowners.
+	CodeownersRoot string `json:"codeownersRoot,omitempty" yaml:"codeownersRoot,omitempty"`
+	// Ownership may be set instead of Codeowners, for example to use a
+	// CODEOWNERS file embedded in the binary.
+	Ownership *Ownership `json:"-" yaml:"-"`
+}
+
+// RoutingRule matches events by their conditions. Every condition which is
+// set must match, so a rule with no conditions matches all events.
+type RoutingRule struct {
+	// Package matches the package, or any subpackage, of the innermost
+	// in-app frame where the error originated. This is the frame which
+	// created the innermost error with a stack trace, or the glog call
+	// site if no error has one.
+	Package string `json:"package,omitempty" yaml:"package,omitempty"`
+	// Severity matches the glog severity, such as ERROR or FATAL.
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	// Tags matches events which have all of the given tag values.
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// ErrorType matches the type of any error in the chain, as formatted by
+	// %T, for example "*os.PathError".
+	ErrorType string `json:"errorType,omitempty" yaml:"errorType,omitempty"`
+
+	// DSNs are the DSNs the event is sent to.
+	DSNs []string `json:"dsns" yaml:"dsns"`
+	// Continue causes the following rules to also be evaluated, sending the
+	// event to the DSNs of every matching rule.
+	Continue bool `json:"continue,omitempty" yaml:"continue,omitempty"`
+}
+
+// init loads the CODEOWNERS file, if any, and returns an error if the
+// routing configuration is unusable.
+func (r *Routing) init() error {
+	if r.Codeowners != "" && r.Ownership == nil {
+		o, err := LoadCodeowners(r.Codeowners, r.CodeownersRoot)
+		if err != nil {
+			return err
+		}
+		r.Ownership = o
+	}
+	for i, rule := range r.Rules {
+		if len(rule.DSNs) == 0 {
+			return fmt.Errorf("routing rule %d has no DSNs", i)
+		}
+		if rule.Severity != "" {
+			if _, err := severityRank(rule.Severity); err != nil {
+				return fmt.Errorf("routing rule %d has %v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dsns returns every DSN named in the routing configuration.
+func (r Routing) dsns() []string {
+	dsns := append([]string(nil), r.Default...)
+	for _, rule := range r.Rules {
+		dsns = append(dsns, rule.DSNs...)
+	}
+	return dsns
+}
+
+// route returns the DSNs the event should be sent to, or nil if it matches
+// no rule and there is no default.
+func (r Routing) route(match *eventMatch) []string {
+	var dsns []string
+	for _, rule := range r.Rules {
+		if !match.matches(rule) {
+			continue
+		}
+		dsns = appendUnique(dsns, rule.DSNs...)
+		if !rule.Continue {
+			break
+		}
+	}
+	if len(dsns) == 0 {
+		return r.Default
+	}
+	return dsns
+}
+
+// eventMatch evaluates rule conditions against an event, computing the
+// origin package and error types only when a rule needs them. At most
+// maxErrorDepth wrapped errors are considered.
+type eventMatch struct {
+	glogEvent     glog.Event
+	event         *sentry.Event
+	maxErrorDepth int
+
+	origin     *sentry.Stacktrace
+	errorTypes []string
+}
+
+func (m *eventMatch) matches(rule RoutingRule) bool {
+	if rule.Severity != "" && !strings.EqualFold(rule.Severity, m.glogEvent.Severity) {
+		return false
+	}
+	for k, v := range rule.Tags {
+		if m.event.Tags[k] != v {
+			return false
+		}
+	}
+	if rule.Package != "" {
+		pkg := m.originPackage()
+		if pkg != rule.Package && !strings.HasPrefix(pkg, rule.Package+"/") {
+			return false
+		}
+	}
+	if rule.ErrorType != "" {
+		found := false
+		for _, t := range m.errorChainTypes() {
+			if t == rule.ErrorType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// originTrace returns the stack trace of the innermost error which has
+// one, or the glog call site if none do.
+func (m *eventMatch) originTrace() *sentry.Stacktrace {
+	if m.origin != nil {
+		return m.origin
+	}
+	m.origin = stacktrace.ExtractFrames(m.glogEvent.StackTrace, nil)
+	for _, err := range errorChain(m.glogEvent, m.maxErrorDepth) {
+		if trace := stacktrace.ExtractStacktrace(err); trace != nil && len(trace.Frames) > 0 {
+			m.origin = trace
+		}
+	}
+	return m.origin
+}
+
+func (m *eventMatch) originPackage() string {
+	// Frames are ordered outermost first.
+	frames := m.originTrace().Frames
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i].InApp {
+			return frames[i].Module
+		}
+	}
+	return ""
+}
+
+func (m *eventMatch) errorChainTypes() []string {
+	if m.errorTypes == nil {
+		m.errorTypes = []string{}
+		for _, err := range errorChain(m.glogEvent, m.maxErrorDepth) {
+			m.errorTypes = append(m.errorTypes, fmt.Sprintf("%T", err))
+		}
+	}
+	return m.errorTypes
+}
+
+// errorChain returns the errors passed to glog, followed by up to
+// maxErrorDepth errors they wrap, outermost first.
+func errorChain(e glog.Event, maxErrorDepth int) []error {
+	var chain []error
+	for _, d := range e.Data {
+		arg, ok := d.(glog.ErrorArg)
+		if !ok {
+			continue
+		}
+		err := arg.Error
+		for i := 0; i < maxErrorDepth && err != nil; i++ {
+			chain = append(chain, err)
+			switch previous := err.(type) {
+			case interface{ Unwrap() error }:
+				err = previous.Unwrap()
+			case interface{ Cause() error }:
+				err = previous.Cause()
+			default:
+				err = nil
+			}
+		}
+	}
+	return chain
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
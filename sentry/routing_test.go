@@ -0,0 +1,188 @@
+package sentry_test
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+	"golang.org/x/xerrors"
+)
+
+// routeEvent sends the event through CaptureErrorsWithRouting, with the
+// server's "primary" project as the first DSN, and returns the projects it
+// was sent to.
+func routeEvent(server *sentrytest.Server, routing sentry.Routing, e glog.Event, options ...sentry.Option) []string {
+	server.Reset()
+	comm := make(chan glog.Event)
+	done := make(chan struct{})
+	go func() {
+		sentry.CaptureErrorsWithRouting("example",
+			[]string{server.DSN("primary")},
+			routing,
+			sentrygo.ClientOptions{},
+			comm,
+			options...)
+		close(done)
+	}()
+	comm <- e
+	close(comm)
+	<-done
+
+	var projects []string
+	for _, e := range server.Events() {
+		projects = append(projects, e.Project)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+func TestRoutingPackage(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	routing := sentry.Routing{Rules: []sentry.RoutingRule{
+		{Package: "github.com/yext/other", DSNs: []string{dsn("a")}},
+		{Package: "github.com/yext/glog-contrib", DSNs: []string{dsn("b")}},
+	}}
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	assert.Equal(t, []string{"b"}, routeEvent(server, routing, e))
+}
+
+func TestRoutingSeverity(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	routing := sentry.Routing{Rules: []sentry.RoutingRule{
+		{Severity: "FATAL", DSNs: []string{dsn("a")}},
+	}}
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	assert.Equal(t, []string{"primary"}, routeEvent(server, routing, e))
+	e = withCallers(glog.Event{Severity: "FATAL", Message: []byte("message")})
+	assert.Equal(t, []string{"a"}, routeEvent(server, routing, e, sentry.WithThreadDumps(true)))
+}
+
+func TestRoutingTagFanOut(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	routing := sentry.Routing{Rules: []sentry.RoutingRule{
+		{Tags: map[string]string{"team": "search"}, DSNs: []string{dsn("a")}, Continue: true},
+		{Tags: map[string]string{"area": "indexing"}, DSNs: []string{dsn("a"), dsn("b")}},
+	}}
+	e := withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("message"),
+		Data:     []interface{}{sentry.Tag("team", "search"), sentry.Tag("area", "indexing")},
+	})
+	assert.Equal(t, []string{"a", "b"}, routeEvent(server, routing, e))
+
+	e.Data = []interface{}{sentry.Tag("team", "search")}
+	assert.Equal(t, []string{"a"}, routeEvent(server, routing, e))
+}
+
+func TestRoutingErrorType(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	routing := sentry.Routing{Rules: []sentry.RoutingRule{
+		{ErrorType: "*fs.PathError", DSNs: []string{dsn("a")}},
+	}}
+	_, err := os.Open("/does/not/exist")
+	e := withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("message"),
+		Data:     []interface{}{glog.ErrorArg{Error: xerrors.Errorf("opening: %w", err)}},
+	})
+	assert.Equal(t, []string{"a"}, routeEvent(server, routing, e))
+
+	e.Data = []interface{}{glog.ErrorArg{Error: xerrors.New("other")}}
+	assert.Equal(t, []string{"primary"}, routeEvent(server, routing, e))
+}
+
+func TestRoutingDefault(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	routing := sentry.Routing{
+		Rules:   []sentry.RoutingRule{{Severity: "FATAL", DSNs: []string{dsn("a")}}},
+		Default: []string{dsn("b")},
+	}
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	assert.Equal(t, []string{"b"}, routeEvent(server, routing, e))
+}
+
+func TestRoutingAltDsnTakesPrecedence(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	routing := sentry.Routing{Rules: []sentry.RoutingRule{{DSNs: []string{dsn("a"), dsn("b")}}}}
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message")})
+	assert.Equal(t, []string{"a", "b"}, routeEvent(server, routing, e))
+
+	e.Data = []interface{}{sentry.AltDsn(dsn("b"))}
+	assert.Equal(t, []string{"b"}, routeEvent(server, routing, e))
+}
+
+func TestRoutingInvalidRule(t *testing.T) {
+	assert.Panics(t, func() {
+		sentry.CaptureErrorsWithRouting("example", []string{"https://public@sentry.example.com/1"},
+			sentry.Routing{Rules: []sentry.RoutingRule{{Severity: "FATAL"}}},
+			sentrygo.ClientOptions{}, nil)
+	})
+}
+
+func TestRoutingCopiesEvents(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	dsn := server.DSN
+
+	cfg := sentry.Config{
+		DSNs: []string{dsn("primary")},
+		Routing: sentry.Routing{Rules: []sentry.RoutingRule{
+			{Tags: map[string]string{"team": "search"}, DSNs: []string{dsn("a"), dsn("b")}},
+		}},
+	}
+	// Each client modifies the exceptions of the events it sends, which
+	// must not affect the events sent by the other.
+	opts := sentrygo.ClientOptions{BeforeSend: func(e *sentrygo.Event, _ *sentrygo.EventHint) *sentrygo.Event {
+		for i := range e.Exception {
+			e.Exception[i].Value += " (sent)"
+			frames := e.Exception[i].Stacktrace.Frames
+			frames[0].Function += " (sent)"
+		}
+		return e
+	}}
+	b, err := sentry.NewBackend(cfg, opts)
+	assert.NoError(t, err)
+
+	comm := make(chan glog.Event, 1)
+	comm <- withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("message"),
+		Data:     []interface{}{sentry.Tag("team", "search")},
+	})
+	close(comm)
+	b.Run(comm)
+
+	events := server.Events()
+	if assert.Len(t, events, 2) {
+		for _, e := range events {
+			ex := e.Exception[len(e.Exception)-1]
+			assert.True(t, strings.HasSuffix(ex.Value, " (sent)"))
+			assert.NotContains(t, ex.Value, "(sent) (sent)")
+			assert.NotContains(t, ex.Stacktrace.Frames[0].Function, "(sent) (sent)")
+		}
+	}
+}
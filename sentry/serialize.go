@@ -0,0 +1,278 @@
+package sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Serializer converts arbitrary values into values which can always be
+// encoded as JSON, so that data attached to glog events can't prevent
+// Sentry events from being sent. Channels, functions and cycles are
+// replaced with descriptions, and the size of the result is limited.
+// Limits which are not set are taken from DefaultSerializer.
+type Serializer struct {
+	// MaxDepth is the maximum depth of nested values. Deeper values are
+	// replaced with their type.
+	MaxDepth int
+	// MaxItems is the maximum number of elements of slices and maps, and
+	// fields of structs.
+	MaxItems int
+	// MaxStringLength is the maximum length of strings, in bytes.
+	MaxStringLength int
+}
+
+// DefaultSerializer is the Serializer used unless another is set with
+// WithSerializer.
+var DefaultSerializer = Serializer{
+	MaxDepth:        8,
+	MaxItems:        100,
+	MaxStringLength: 4096,
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	errorType         = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// Serialize converts v into a value consisting only of nil, booleans,
+// numbers, strings, json.RawMessage, slices and string-keyed maps.
+// Values implementing json.Marshaler are encoded with it, and errors and
+// fmt.Stringers are converted to strings.
+func (s Serializer) Serialize(v interface{}) interface{} {
+	if s.MaxDepth <= 0 {
+		s.MaxDepth = DefaultSerializer.MaxDepth
+	}
+	if s.MaxItems <= 0 {
+		s.MaxItems = DefaultSerializer.MaxItems
+	}
+	if s.MaxStringLength <= 0 {
+		s.MaxStringLength = DefaultSerializer.MaxStringLength
+	}
+	return s.serialize(reflect.ValueOf(v), 0, map[uintptr]bool{})
+}
+
+// serialize converts v, at the given depth. The addresses of the pointers,
+// maps and slices being serialized are recorded in seen, to detect cycles.
+func (s Serializer) serialize(v reflect.Value, depth int, seen map[uintptr]bool) (out interface{}) {
+	if !v.IsValid() {
+		return nil
+	}
+	if depth > s.MaxDepth {
+		return fmt.Sprintf("<%s>", v.Type())
+	}
+
+	// Methods of the value may panic, e.g. when called on a nil pointer.
+	defer func() {
+		if r := recover(); r != nil {
+			out = fmt.Sprintf("<%s: panic: %v>", v.Type(), r)
+		}
+	}()
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.CanInterface() {
+		switch {
+		case v.Type().Implements(jsonMarshalerType):
+			b, err := v.Interface().(json.Marshaler).MarshalJSON()
+			if err != nil || !json.Valid(b) {
+				return fmt.Sprintf("<%s: invalid JSON>", v.Type())
+			}
+			if len(b) > s.MaxStringLength {
+				return s.truncate(string(b))
+			}
+			return json.RawMessage(b)
+		case v.Type().Implements(errorType):
+			return s.truncate(v.Interface().(error).Error())
+		case v.Type().Implements(stringerType):
+			return s.truncate(v.Interface().(fmt.Stringer).String())
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Sprint(f)
+		}
+		return f
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Complex())
+	case reflect.String:
+		return s.truncate(v.String())
+	case reflect.Interface:
+		return s.serialize(v.Elem(), depth, seen)
+	case reflect.Ptr:
+		if seen[v.Pointer()] {
+			return fmt.Sprintf("<%s: cycle>", v.Type())
+		}
+		seen[v.Pointer()] = true
+		defer delete(seen, v.Pointer())
+		return s.serialize(v.Elem(), depth, seen)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if b := v.Bytes(); utf8.Valid(b) {
+				return s.truncate(string(b))
+			}
+			return s.truncate(fmt.Sprintf("%x", v.Bytes()))
+		}
+		if seen[v.Pointer()] {
+			return fmt.Sprintf("<%s: cycle>", v.Type())
+		}
+		seen[v.Pointer()] = true
+		defer delete(seen, v.Pointer())
+		return s.serializeList(v, depth, seen)
+	case reflect.Array:
+		return s.serializeList(v, depth, seen)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		if seen[v.Pointer()] {
+			return fmt.Sprintf("<%s: cycle>", v.Type())
+		}
+		seen[v.Pointer()] = true
+		defer delete(seen, v.Pointer())
+		return s.serializeMap(v, depth, seen)
+	case reflect.Struct:
+		return s.serializeStruct(v, depth, seen)
+	default:
+		// Channels, functions and unsafe pointers.
+		return fmt.Sprintf("<%s>", v.Type())
+	}
+}
+
+func (s Serializer) serializeList(v reflect.Value, depth int, seen map[uintptr]bool) []interface{} {
+	n := v.Len()
+	if n > s.MaxItems {
+		n = s.MaxItems
+	}
+	out := make([]interface{}, 0, n+1)
+	for i := 0; i < n; i++ {
+		out = append(out, s.serialize(v.Index(i), depth+1, seen))
+	}
+	if v.Len() > n {
+		out = append(out, fmt.Sprintf("<%d more>", v.Len()-n))
+	}
+	return out
+}
+
+func (s Serializer) serializeMap(v reflect.Value, depth int, seen map[uintptr]bool) map[string]interface{} {
+	keys := make(map[string]reflect.Value, v.Len())
+	names := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		name := s.truncate(fmt.Sprint(s.serialize(k, depth+1, seen)))
+		keys[name] = k
+		names = append(names, name)
+	}
+	// Sort the keys so that the same items are kept when truncating.
+	sort.Strings(names)
+
+	out := map[string]interface{}{}
+	for i, name := range names {
+		if i == s.MaxItems {
+			out["..."] = fmt.Sprintf("<%d more>", len(names)-i)
+			break
+		}
+		out[name] = s.serialize(v.MapIndex(keys[name]), depth+1, seen)
+	}
+	return out
+}
+
+// serializeStruct converts the exported fields of a struct to a map, named
+// as they would be by encoding/json.
+func (s Serializer) serializeStruct(v reflect.Value, depth int, seen map[uintptr]bool) map[string]interface{} {
+	out := map[string]interface{}{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		if len(out) == s.MaxItems {
+			out["..."] = fmt.Sprintf("<%d more>", t.NumField()-i)
+			break
+		}
+		out[name] = s.serialize(v.Field(i), depth+1, seen)
+	}
+	return out
+}
+
+// truncate limits the length of str to MaxStringLength, without splitting
+// a UTF-8 sequence.
+func (s Serializer) truncate(str string) string {
+	if len(str) <= s.MaxStringLength {
+		return str
+	}
+	n := s.MaxStringLength
+	for n > 0 && !utf8.RuneStart(str[n]) {
+		n--
+	}
+	return str[:n] + "..."
+}
+
+// addData adds a serialized value to the data attached to an event. If
+// the key is already set to a different value, the value is added with a
+// numbered suffix, such as "key#2", rather than replacing it.
+func addData(data map[string]interface{}, key string, value interface{}) {
+	k := key
+	for i := 2; ; i++ {
+		existing, ok := data[k]
+		if !ok {
+			data[k] = value
+			return
+		}
+		if reflect.DeepEqual(existing, value) {
+			return
+		}
+		k = fmt.Sprintf("%s#%d", key, i)
+	}
+}
+
+// Sentry truncates tag values longer than this.
+const maxTagLength = 200
+
+// tagValue formats a serialized value as a tag.
+func tagValue(value interface{}) string {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case json.RawMessage:
+		str = string(v)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		str = string(b)
+	default:
+		str = fmt.Sprint(v)
+	}
+	str = strings.ReplaceAll(str, "\n", " ")
+	return Serializer{MaxStringLength: maxTagLength - len("...")}.truncate(str)
+}
@@ -0,0 +1,75 @@
+package sentry_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+)
+
+type node struct {
+	Name     string `json:"name"`
+	Next     *node  `json:"next,omitempty"`
+	Ignored  string `json:"-"`
+	internal string
+}
+
+func TestSerialize(t *testing.T) {
+	s := sentry.DefaultSerializer
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	assert.Nil(t, s.Serialize(nil))
+	assert.Nil(t, s.Serialize((*node)(nil)))
+	assert.Equal(t, int64(3), s.Serialize(3))
+	assert.Equal(t, "NaN", s.Serialize(math.NaN()))
+	assert.Equal(t, "text", s.Serialize([]byte("text")))
+	assert.Equal(t, json.RawMessage(`"2024-01-02T15:04:05Z"`), s.Serialize(ts))
+	assert.Equal(t, "failed", s.Serialize(errors.New("failed")))
+	assert.Equal(t, "1m0s", s.Serialize(time.Minute))
+	assert.Equal(t, "<chan int>", s.Serialize(make(chan int)))
+	assert.Equal(t, "<func()>", s.Serialize(func() {}))
+	assert.Equal(t, map[string]interface{}{"1": []interface{}{"a", "b"}}, s.Serialize(map[int][]string{1: {"a", "b"}}))
+
+	n := &node{Name: "a", Ignored: "x", internal: "y"}
+	n.Next = &node{Name: "b", Next: n}
+	assert.Equal(t, map[string]interface{}{
+		"name": "a",
+		"next": map[string]interface{}{"name": "b", "next": "<*sentry_test.node: cycle>"},
+	}, s.Serialize(n))
+
+	// The result can always be encoded
+	_, err := json.Marshal(s.Serialize(map[string]interface{}{"n": n, "f": func() {}, "c": make(chan int)}))
+	assert.NoError(t, err)
+}
+
+func TestSerializeLimits(t *testing.T) {
+	s := sentry.Serializer{MaxDepth: 1, MaxItems: 2, MaxStringLength: 4}
+
+	assert.Equal(t, "abcd...", s.Serialize("abcdefgh"))
+	assert.Equal(t, []interface{}{int64(1), int64(2), "<1 more>"}, s.Serialize([]int{1, 2, 3}))
+	assert.Equal(t, map[string]interface{}{"a": int64(1), "b": int64(2), "...": "<1 more>"},
+		s.Serialize(map[string]int{"c": 3, "b": 2, "a": 1}))
+	assert.Equal(t, []interface{}{[]interface{}{"<[]int>"}}, s.Serialize([][][]int{{{1}}}))
+}
+
+func TestEventData(t *testing.T) {
+	e := withCallers(glog.Event{Severity: "ERROR", Message: []byte("message"), Data: []interface{}{
+		map[string]interface{}{"user": "alice", "site": "eu", "callback": func() {}},
+		map[string]interface{}{"user": "bob", "site": "eu", "request": strings.Repeat("x", 300)},
+	}})
+
+	s, _ := sentry.FromGlogEvent(e, true, sentry.WithEnrichers(), sentry.WithTagKeys("site", "request"))
+	assert.Equal(t, map[string]interface{}{
+		"user":     "alice",
+		"user#2":   "bob",
+		"callback": "<func()>",
+	}, s.Extra["Data"])
+	assert.Equal(t, "eu", s.Tags["site"])
+	assert.Len(t, s.Tags["request"], 200)
+}
@@ -0,0 +1,523 @@
+package sentry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// An on-disk spool for Sentry events, used so that events are not lost
+// while Sentry is unreachable.
+
+const spoolFileSuffix = ".envelope"
+
+// SpoolOptions configures a SpoolTransport. Only Dir is required.
+type SpoolOptions struct {
+	// Dir is the directory in which undelivered events are stored.
+	// It is created if it does not exist.
+	Dir string
+	// MaxBytes is the maximum total size of the spool. The oldest events
+	// are dropped once it is exceeded. Defaults to 50MB.
+	MaxBytes int64
+	// MaxAge is the maximum time an event is kept before it is dropped.
+	// Defaults to 72 hours.
+	MaxAge time.Duration
+	// InitialBackoff is the time waited after the first failed delivery
+	// attempt, which doubles after each further failure. Defaults to 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time waited between delivery attempts.
+	// Defaults to 5 minutes.
+	MaxBackoff time.Duration
+}
+
+func (o *SpoolOptions) setDefaults() {
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = 50 * 1024 * 1024
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 72 * time.Hour
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Minute
+	}
+}
+
+// SpoolTransport is a sentry.Transport which sends events asynchronously,
+// and writes those which cannot be sent due to network errors, rate
+// limiting, or server errors to a spool directory. Spooled events are
+// retried with exponential backoff, and only removed once they are
+// delivered. Events left in the directory when the process exits are sent
+// by the next SpoolTransport created with the same directory.
+//
+// A SpoolTransport may be set as the Transport of the client options passed
+// to CaptureErrors, in which case a single spool is shared by all DSNs.
+type SpoolTransport struct {
+	*spool
+	dsn *sentry.Dsn
+}
+
+// NewSpoolTransport creates the spool directory, if needed, and starts
+// delivering any events already present in it. Spooled events contain the
+// DSN they are sent to, including its key, so the directory and files are
+// only accessible by the current user.
+func NewSpoolTransport(opts SpoolOptions) (*SpoolTransport, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("spool directory must be specified")
+	}
+	opts.setDefaults()
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	s := &spool{
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		client:   &http.Client{Timeout: 30 * time.Second},
+		clients:  make(map[string]*http.Client),
+		passDone: make(chan struct{}),
+	}
+	go s.run()
+	return &SpoolTransport{spool: s}, nil
+}
+
+// cloneTransport returns a transport for another client sharing this spool.
+func (t *SpoolTransport) cloneTransport() sentry.Transport {
+	return &SpoolTransport{spool: t.spool}
+}
+
+// Configure sets the DSN that events are sent to, and the HTTP client used
+// to deliver them if one is specified in the options.
+func (t *SpoolTransport) Configure(options sentry.ClientOptions) {
+	dsn, err := sentry.NewDsn(options.Dsn)
+	if err != nil {
+		sentry.Logger.Printf("spool: %v", err)
+		return
+	}
+	t.dsn = dsn
+
+	var client *http.Client
+	if options.HTTPClient != nil {
+		client = options.HTTPClient
+	} else if options.HTTPTransport != nil {
+		client = &http.Client{Transport: options.HTTPTransport, Timeout: 30 * time.Second}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if client != nil {
+		t.clients[dsn.String()] = client
+	} else {
+		delete(t.clients, dsn.String())
+	}
+}
+
+// SendEvent queues the event to be delivered asynchronously. It is written
+// to the spool if it cannot be sent, or if earlier events are still spooled.
+func (t *SpoolTransport) SendEvent(event *sentry.Event) {
+	if t.dsn == nil {
+		return
+	}
+	envelope, err := buildEnvelope(event, t.dsn)
+	if err != nil {
+		sentry.Logger.Printf("spool: unable to encode event %s: %v", event.EventID, err)
+		return
+	}
+	t.enqueue(queuedEvent{eventID: string(event.EventID), envelope: envelope})
+	t.notify()
+}
+
+// Flush waits for a delivery attempt of all queued and spooled events, and
+// returns whether they were all delivered. If Sentry is currently
+// unreachable, it returns false immediately, since events remain in the
+// spool.
+func (t *SpoolTransport) Flush(timeout time.Duration) bool {
+	deadline := time.After(timeout)
+
+	t.mu.Lock()
+	failing, running, done := t.failing, t.running, t.passDone
+	t.mu.Unlock()
+	if failing {
+		return false
+	}
+
+	// A pass already in progress may have missed recently written
+	// events, so wait for it to finish before starting another.
+	if running {
+		select {
+		case <-done:
+		case <-deadline:
+			return false
+		}
+		t.mu.Lock()
+		done = t.passDone
+		t.mu.Unlock()
+	}
+
+	t.notify()
+	select {
+	case <-done:
+	case <-deadline:
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.failing
+}
+
+// Close stops delivering events, and waits for a delivery attempt in
+// progress to finish. Events which have not been delivered are left in the
+// spool, to be sent by the next SpoolTransport created with the same
+// directory, as are any events sent after Close. Close stops the spool
+// shared by all clients using the transport.
+func (t *SpoolTransport) Close() {
+	t.closeOnce.Do(func() { close(t.stop) })
+	<-t.done
+}
+
+// maxQueuedEvents is the number of events kept in memory while waiting for
+// their first delivery attempt. Further events are written to the spool.
+const maxQueuedEvents = 100
+
+// queuedEvent is an event which has not yet been written to the spool.
+type queuedEvent struct {
+	eventID  string
+	envelope []byte
+}
+
+// spool is the directory and delivery worker shared by all transports
+// created from the same NewSpoolTransport call.
+type spool struct {
+	opts SpoolOptions
+	wake chan struct{}
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+
+	mu sync.Mutex
+	// client is used for DSNs which have no HTTP client in their options.
+	client   *http.Client
+	clients  map[string]*http.Client
+	queue    []queuedEvent
+	stopped  bool
+	running  bool
+	failing  bool
+	passDone chan struct{}
+}
+
+func (s *spool) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue adds an event to the in-memory queue to be sent by the worker.
+// While earlier events are spooled, or once the worker has stopped, the
+// event is written straight to the spool so that events are delivered in
+// order.
+func (s *spool) enqueue(e queuedEvent) {
+	s.mu.Lock()
+	if !s.failing && !s.stopped && len(s.queue) < maxQueuedEvents {
+		s.queue = append(s.queue, e)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	s.spoolEvent(e)
+}
+
+// dequeue removes and returns the oldest queued event.
+func (s *spool) dequeue() (queuedEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return queuedEvent{}, false
+	}
+	e := s.queue[0]
+	s.queue[0] = queuedEvent{}
+	s.queue = s.queue[1:]
+	return e, true
+}
+
+// spoolQueued writes all queued events to the spool.
+func (s *spool) spoolQueued() {
+	for {
+		e, ok := s.dequeue()
+		if !ok {
+			return
+		}
+		s.spoolEvent(e)
+	}
+}
+
+func (s *spool) spoolEvent(e queuedEvent) {
+	if err := s.write(e.eventID, e.envelope); err != nil {
+		sentry.Logger.Printf("spool: unable to write event %s: %v", e.eventID, err)
+	}
+}
+
+// write atomically adds an envelope to the spool, then drops the oldest
+// events if the spool is over its size limit.
+func (s *spool) write(eventID string, envelope []byte) error {
+	name := fmt.Sprintf("%020d-%s%s", time.Now().UnixNano(), eventID, spoolFileSuffix)
+	tmp := filepath.Join(s.opts.Dir, "."+name)
+	if err := os.WriteFile(tmp, envelope, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.opts.Dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	var total int64
+	files := s.files()
+	for _, f := range files {
+		total += f.Size()
+	}
+	for i := 0; total > s.opts.MaxBytes && i < len(files); i++ {
+		sentry.Logger.Printf("spool: over size limit, dropping %s", files[i].Name())
+		s.remove(files[i])
+		total -= files[i].Size()
+	}
+	return nil
+}
+
+// files returns the spooled envelopes, oldest first.
+func (s *spool) files() []os.FileInfo {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		sentry.Logger.Printf("spool: %v", err)
+		return nil
+	}
+	var files []os.FileInfo
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), spoolFileSuffix) {
+			continue
+		}
+		// The file may have been removed since the directory was read.
+		if info, err := e.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files
+}
+
+func (s *spool) remove(f os.FileInfo) {
+	os.Remove(filepath.Join(s.opts.Dir, f.Name()))
+}
+
+// run delivers events until the spool is closed. After a failed delivery,
+// it waits with exponential backoff before trying again, otherwise it waits
+// for new events. When the spool is closed, queued events are written to
+// the spool.
+func (s *spool) run() {
+	defer close(s.done)
+	defer s.spoolQueued()
+	defer func() {
+		s.mu.Lock()
+		s.stopped = true
+		s.mu.Unlock()
+	}()
+
+	var backoff time.Duration
+	for {
+		s.mu.Lock()
+		s.running = true
+		s.mu.Unlock()
+
+		retryAfter, ok := s.deliver()
+
+		s.mu.Lock()
+		s.running = false
+		s.failing = !ok
+		close(s.passDone)
+		s.passDone = make(chan struct{})
+		s.mu.Unlock()
+
+		if ok {
+			backoff = 0
+			select {
+			case <-s.wake:
+			case <-s.stop:
+				return
+			}
+			continue
+		}
+
+		backoff *= 2
+		if backoff < s.opts.InitialBackoff {
+			backoff = s.opts.InitialBackoff
+		}
+		if backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// deliver sends spooled events in order, then queued events, stopping at
+// the first one which should be retried later. It returns false in that
+// case, along with the delay requested by the server, if any, and the
+// remaining queued events are written to the spool.
+func (s *spool) deliver() (time.Duration, bool) {
+	for _, f := range s.files() {
+		if time.Since(f.ModTime()) > s.opts.MaxAge {
+			sentry.Logger.Printf("spool: dropping expired event %s", f.Name())
+			s.remove(f)
+			continue
+		}
+
+		envelope, err := os.ReadFile(filepath.Join(s.opts.Dir, f.Name()))
+		if err != nil {
+			// The file may have been dropped while over the size limit.
+			continue
+		}
+		retryAfter, retry := s.send(envelope)
+		if retry {
+			s.spoolQueued()
+			return retryAfter, false
+		}
+		s.remove(f)
+	}
+
+	for {
+		e, ok := s.dequeue()
+		if !ok {
+			return 0, true
+		}
+		retryAfter, retry := s.send(e.envelope)
+		if retry {
+			s.spoolEvent(e)
+			s.spoolQueued()
+			return retryAfter, false
+		}
+	}
+}
+
+// send posts the envelope to the DSN in its header, and returns whether it
+// should be retried later.
+func (s *spool) send(envelope []byte) (time.Duration, bool) {
+	var header struct {
+		Dsn string `json:"dsn"`
+	}
+	line, _ := bufio.NewReader(bytes.NewReader(envelope)).ReadBytes('\n')
+	if err := json.Unmarshal(line, &header); err != nil {
+		sentry.Logger.Printf("spool: dropping corrupt envelope: %v", err)
+		return 0, false
+	}
+	dsn, err := sentry.NewDsn(header.Dsn)
+	if err != nil {
+		sentry.Logger.Printf("spool: dropping envelope: %v", err)
+		return 0, false
+	}
+
+	req, err := http.NewRequest(http.MethodPost, dsn.GetAPIURL().String(), bytes.NewReader(envelope))
+	if err != nil {
+		sentry.Logger.Printf("spool: dropping envelope: %v", err)
+		return 0, false
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", authHeader(dsn))
+
+	s.mu.Lock()
+	client, ok := s.clients[dsn.String()]
+	if !ok {
+		client = s.client
+	}
+	s.mu.Unlock()
+	resp, err := client.Do(req)
+	if err != nil {
+		sentry.Logger.Printf("spool: %v", err)
+		return 0, true
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		sentry.Logger.Printf("spool: delivery failed with status %s", resp.Status)
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, true
+	case resp.StatusCode >= 400:
+		// The event was rejected, so retrying it will not help.
+		sentry.Logger.Printf("spool: dropping event rejected with status %s", resp.Status)
+	}
+	return 0, false
+}
+
+// buildEnvelope encodes the event in the envelope format accepted by the
+// Sentry envelope endpoint, including the DSN it should be sent to.
+func buildEnvelope(event *sentry.Event, dsn *sentry.Dsn) ([]byte, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	itemType := event.Type
+	if itemType == "" {
+		itemType = "event"
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	err = enc.Encode(map[string]interface{}{
+		// sent_at is omitted, since Sentry would use it to correct the
+		// timestamp of events which were delayed in the spool.
+		"event_id": event.EventID,
+		"dsn":      dsn.String(),
+		"sdk": map[string]string{
+			"name":    event.Sdk.Name,
+			"version": event.Sdk.Version,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Encode(map[string]interface{}{
+		"type":   itemType,
+		"length": len(body),
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.Write(body)
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func authHeader(dsn *sentry.Dsn) string {
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=glog-contrib/%s, sentry_key=%s",
+		sentry.SDKVersion, dsn.GetPublicKey())
+	if secret := dsn.GetSecretKey(); secret != "" {
+		auth += ", sentry_secret=" + secret
+	}
+	return auth
+}
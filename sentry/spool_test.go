@@ -0,0 +1,218 @@
+package sentry_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog-contrib/sentry"
+)
+
+// envelopeServer records the bodies of envelopes it receives, responding
+// with the configured status code.
+type envelopeServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	status    int
+	envelopes []string
+}
+
+func newEnvelopeServer(status int) *envelopeServer {
+	s := &envelopeServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status == http.StatusOK {
+			s.envelopes = append(s.envelopes, string(body))
+		}
+		w.WriteHeader(s.status)
+	}))
+	return s
+}
+
+func (s *envelopeServer) setStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *envelopeServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.envelopes...)
+}
+
+func (s *envelopeServer) dsn() string {
+	return strings.Replace(s.URL, "http://", "http://public@", 1) + "/1"
+}
+
+func newSpoolClient(t *testing.T, dir, dsn string) (*sentrygo.Client, *sentry.SpoolTransport) {
+	spool, err := sentry.NewSpoolTransport(sentry.SpoolOptions{
+		Dir:            dir,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	client, err := sentrygo.NewClient(sentrygo.ClientOptions{Dsn: dsn, Transport: spool})
+	assert.NoError(t, err)
+	return client, spool
+}
+
+func spooledFiles(t *testing.T, dir string) int {
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	return len(entries)
+}
+
+func TestSpoolDelivers(t *testing.T) {
+	server := newEnvelopeServer(http.StatusOK)
+	defer server.Close()
+	dir := t.TempDir()
+
+	client, _ := newSpoolClient(t, dir, server.dsn())
+	client.CaptureMessage("test message", nil, nil)
+	assert.True(t, client.Flush(5*time.Second))
+
+	received := server.received()
+	assert.Len(t, received, 1)
+	assert.Contains(t, received[0], `"type":"event"`)
+	assert.Contains(t, received[0], "test message")
+	assert.Equal(t, 0, spooledFiles(t, dir), "delivered events are removed")
+}
+
+func TestSpoolRetries(t *testing.T) {
+	server := newEnvelopeServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	dir := filepath.Join(t.TempDir(), "spool")
+
+	client, _ := newSpoolClient(t, dir, server.dsn())
+	client.CaptureMessage("test message", nil, nil)
+	assert.False(t, client.Flush(100*time.Millisecond))
+	assert.Equal(t, 1, spooledFiles(t, dir), "undelivered events are kept")
+
+	// Spooled events contain the DSN's key, so are private to the user
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	server.setStatus(http.StatusOK)
+	assert.Eventually(t, func() bool {
+		return len(server.received()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return spooledFiles(t, dir) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSpoolReplaysAfterRestart(t *testing.T) {
+	server := newEnvelopeServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	dir := t.TempDir()
+
+	client, _ := newSpoolClient(t, dir, server.dsn())
+	client.CaptureMessage("test message", nil, nil)
+	client.Flush(100 * time.Millisecond)
+	assert.Equal(t, 1, spooledFiles(t, dir))
+
+	// A new transport for the same directory sends the spooled event,
+	// using the DSN recorded in the envelope.
+	other := newEnvelopeServer(http.StatusOK)
+	defer other.Close()
+	server.setStatus(http.StatusOK)
+	newSpoolClient(t, dir, other.dsn())
+
+	assert.Eventually(t, func() bool {
+		return len(server.received()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, other.received())
+}
+
+func TestSpoolDropsRejectedEvents(t *testing.T) {
+	server := newEnvelopeServer(http.StatusBadRequest)
+	defer server.Close()
+	dir := t.TempDir()
+
+	client, _ := newSpoolClient(t, dir, server.dsn())
+	client.CaptureMessage("test message", nil, nil)
+	assert.True(t, client.Flush(5*time.Second))
+	assert.Equal(t, 0, spooledFiles(t, dir))
+}
+
+func TestSpoolSizeLimit(t *testing.T) {
+	server := newEnvelopeServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	dir := t.TempDir()
+
+	spool, err := sentry.NewSpoolTransport(sentry.SpoolOptions{
+		Dir:            dir,
+		MaxBytes:       1,
+		InitialBackoff: time.Hour,
+	})
+	assert.NoError(t, err)
+	client, err := sentrygo.NewClient(sentrygo.ClientOptions{Dsn: server.dsn(), Transport: spool})
+	assert.NoError(t, err)
+
+	client.CaptureMessage("first message", nil, nil)
+	client.CaptureMessage("second message", nil, nil)
+	assert.False(t, client.Flush(5*time.Second))
+	assert.Equal(t, 0, spooledFiles(t, dir), "events over the size limit are dropped")
+}
+
+func TestSpoolSendsBeforeSpooling(t *testing.T) {
+	dir := t.TempDir()
+	var spooled []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spooled = append(spooled, spooledFiles(t, dir))
+	}))
+	defer server.Close()
+
+	client, _ := newSpoolClient(t, dir, strings.Replace(server.URL, "http://", "http://public@", 1)+"/1")
+	client.CaptureMessage("test message", nil, nil)
+	assert.True(t, client.Flush(5*time.Second))
+	assert.Equal(t, []int{0}, spooled, "events are only spooled if they cannot be sent")
+}
+
+func TestSpoolClose(t *testing.T) {
+	server := newEnvelopeServer(http.StatusServiceUnavailable)
+	defer server.Close()
+	dir := t.TempDir()
+
+	client, spool := newSpoolClient(t, dir, server.dsn())
+	client.CaptureMessage("first message", nil, nil)
+	assert.False(t, client.Flush(100*time.Millisecond))
+	spool.Close()
+	spool.Close()
+
+	// Events sent after Close are spooled for the next transport.
+	server.setStatus(http.StatusOK)
+	client.CaptureMessage("second message", nil, nil)
+	assert.Empty(t, server.received())
+	assert.Equal(t, 2, spooledFiles(t, dir))
+
+	_, spool = newSpoolClient(t, dir, server.dsn())
+	defer spool.Close()
+	assert.Eventually(t, func() bool {
+		return len(server.received()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	received := server.received()
+	assert.Contains(t, received[0], "first message")
+	assert.Contains(t, received[1], "second message")
+}
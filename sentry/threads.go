@@ -0,0 +1,66 @@
+package sentry
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/stacktrace"
+)
+
+// dumpThreads is added to the data of recovered panics and FATAL events
+// which are captured directly on the goroutine which caused them, so that
+// the stacks of all goroutines can be taken at that time.
+type dumpThreads struct{}
+
+func hasDumpThreads(e glog.Event) bool {
+	for _, d := range e.Data {
+		if _, ok := d.(dumpThreads); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildThreads captures the stacks of all goroutines and converts them into
+// Sentry threads. The goroutine whose stack contains the innermost frame of
+// callSite, if any, is marked as crashed.
+func buildThreads(callSite []uintptr) []sentry.Thread {
+	var function string
+	var line int
+	if len(callSite) > 0 {
+		frame, _ := runtime.CallersFrames(callSite).Next()
+		function, line = frame.Function, frame.Line
+	}
+
+	var threads []sentry.Thread
+	for _, g := range stacktrace.AllGoroutines() {
+		crashed := function != "" && g.HasFrame(function, line)
+		threads = append(threads, sentry.Thread{
+			ID:         strconv.Itoa(g.ID),
+			Name:       threadName(g),
+			Stacktrace: g.Stacktrace(),
+			Crashed:    crashed,
+			Current:    crashed,
+		})
+	}
+	return threads
+}
+
+// threadName describes the state of the goroutine, for example
+// "chan receive (blocked 3m0s)".
+func threadName(g stacktrace.Goroutine) string {
+	var details []string
+	if g.Wait > 0 {
+		details = append(details, "blocked "+g.Wait.String())
+	}
+	if g.LockedToThread {
+		details = append(details, "locked to thread")
+	}
+	if len(details) == 0 {
+		return g.State
+	}
+	return g.State + " (" + strings.Join(details, ", ") + ")"
+}
@@ -0,0 +1,95 @@
+package sentry_test
+
+import (
+	"runtime"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+)
+
+func TestThreadsOnlyForDirectCaptures(t *testing.T) {
+	// Thread dumps are only attached to events captured on the goroutine
+	// which caused them, by Fatal and the panic helpers.
+	for _, severity := range []string{"ERROR", "FATAL"} {
+		e, _ := sentry.FromGlogEvent(withCallers(glog.Event{Severity: severity, Message: []byte("message")}), true,
+			sentry.WithThreadDumps(true))
+		assert.Empty(t, e.Threads, severity)
+	}
+}
+
+func TestPanicThreads(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	b, err := sentry.NewBackend(sentry.Config{DSNs: []string{server.DSN("1")}}, sentrygo.ClientOptions{},
+		sentry.WithThreadDumps(true))
+	require.NoError(t, err)
+	comm := make(chan glog.Event)
+	defer close(comm)
+	go b.Run(comm)
+	// The backend is running once it has received an event
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("started")})
+
+	block := make(chan struct{})
+	defer close(block)
+	go func() { <-block }()
+	func() {
+		defer sentry.Recover()
+		panic("test panic")
+	}()
+
+	events := server.RequireEvents(t, 2)
+	var crashed []sentrygo.Thread
+	for _, e := range events {
+		if e.Message != "panic: test panic" {
+			assert.Empty(t, e.Threads)
+			continue
+		}
+		assert.True(t, len(e.Threads) >= 2, "includes all goroutines")
+		for _, th := range e.Threads {
+			if th.Crashed {
+				crashed = append(crashed, th)
+			}
+		}
+	}
+	if assert.Len(t, crashed, 1, "the panicking goroutine is marked as crashed") {
+		assert.True(t, crashed[0].Current)
+		frames := crashed[0].Stacktrace.Frames
+		var functions []string
+		for _, f := range frames {
+			functions = append(functions, f.Function)
+		}
+		assert.Contains(t, functions, "TestPanicThreads.func2")
+	}
+}
+
+func TestFatalEventsWithoutThreadDumps(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+	b, err := sentry.NewBackend(sentry.Config{DSNs: []string{server.DSN("1")}, MinSeverity: "FATAL"},
+		sentrygo.ClientOptions{})
+	require.NoError(t, err)
+
+	comm := make(chan glog.Event, 2)
+	comm <- withCallers(glog.Event{Severity: "ERROR", Message: []byte("error message")})
+	comm <- withCallers(glog.Event{Severity: "FATAL", Message: []byte("fatal message")})
+	close(comm)
+	b.Run(comm)
+
+	events := server.Events()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "fatal message", events[0].Message)
+		assert.Empty(t, events[0].Threads)
+	}
+}
+
+// withCallers sets the stack trace of the event to that of the caller.
+func withCallers(e glog.Event) glog.Event {
+	callers := make([]uintptr, 20)
+	e.StackTrace = callers[:runtime.Callers(2, callers)]
+	return e
+}
@@ -0,0 +1,119 @@
+package sentry
+
+import (
+	"context"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tags set on events linked to a trace, so that they can be searched for.
+const (
+	TraceIDTag = "trace_id"
+	SpanIDTag  = "span_id"
+)
+
+// The name and attribute of the span event recording the Sentry event ID.
+const (
+	SpanEventName   = "sentry.event"
+	SpanEventIDAttr = "sentry.event_id"
+)
+
+// The name of the Sentry context linking an event to a trace.
+const traceContext = "trace"
+
+// traceLink is the trace and span which produced a glog event.
+type traceLink struct {
+	span    trace.SpanContext
+	parent  trace.SpanContext
+	current trace.Span
+}
+
+// traceFromData returns the trace and span of the last context.Context or
+// TraceParent attribute of a glog event which identifies one, if any.
+func traceFromData(data []interface{}) *traceLink {
+	var link *traceLink
+	for _, d := range data {
+		var l *traceLink
+		switch t := d.(type) {
+		case context.Context:
+			l = traceFromContext(t)
+		case traceParent:
+			l = traceFromHeader(string(t))
+		}
+		if l != nil {
+			link = l
+		}
+	}
+	return link
+}
+
+// traceFromContext returns the span carried by the context, if any.
+func traceFromContext(ctx context.Context) *traceLink {
+	span := trace.SpanFromContext(ctx)
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return nil
+	}
+	link := &traceLink{span: sc, current: span}
+	// Spans of the OpenTelemetry SDK expose their parent.
+	if p, ok := span.(interface{ Parent() trace.SpanContext }); ok {
+		link.parent = p.Parent()
+	}
+	return link
+}
+
+var traceParentRe = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// traceFromHeader parses a W3C traceparent header. The parent ID of the
+// header identifies the span which produced the event.
+func traceFromHeader(header string) *traceLink {
+	m := traceParentRe.FindStringSubmatch(header)
+	if m == nil || m[1] == "ff" {
+		return nil
+	}
+	traceID, err := trace.TraceIDFromHex(m[2])
+	if err != nil {
+		return nil
+	}
+	spanID, err := trace.SpanIDFromHex(m[3])
+	if err != nil {
+		return nil
+	}
+	flags, _ := hex.DecodeString(m[4])
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags[0]),
+		Remote:     true,
+	})
+	return &traceLink{span: sc}
+}
+
+// apply sets the trace context and tags of the event.
+func (t *traceLink) apply(e *sentry.Event) {
+	tc := sentry.TraceContext{
+		TraceID: sentry.TraceID(t.span.TraceID()),
+		SpanID:  sentry.SpanID(t.span.SpanID()),
+	}
+	if t.parent.IsValid() {
+		tc.ParentSpanID = sentry.SpanID(t.parent.SpanID())
+	}
+	e.Contexts[traceContext] = tc.Map()
+	e.Tags[TraceIDTag] = t.span.TraceID().String()
+	e.Tags[SpanIDTag] = t.span.SpanID().String()
+}
+
+// recordEvent records the ID of a captured event on the span, if it is
+// recording. Events are captured asynchronously, once glog passes them to
+// the backend, so the span has often ended unless it is long-lived.
+func (t *traceLink) recordEvent(id sentry.EventID) {
+	if t.current == nil || !t.current.IsRecording() {
+		return
+	}
+	t.current.AddEvent(SpanEventName, trace.WithAttributes(
+		attribute.String(SpanEventIDAttr, string(id))))
+}
@@ -0,0 +1,164 @@
+package sentry_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/sentry"
+	"github.com/yext/glog-contrib/sentrytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordingSpan is a span which records its events, as spans of the
+// OpenTelemetry SDK do.
+type recordingSpan struct {
+	trace.Span
+	sc, parent trace.SpanContext
+	events     map[string][]attribute.KeyValue
+	ended      bool
+}
+
+func (s *recordingSpan) SpanContext() trace.SpanContext { return s.sc }
+func (s *recordingSpan) Parent() trace.SpanContext      { return s.parent }
+func (s *recordingSpan) IsRecording() bool              { return !s.ended }
+func (s *recordingSpan) End(...trace.SpanEndOption)     { s.ended = true }
+
+func (s *recordingSpan) AddEvent(name string, options ...trace.EventOption) {
+	config := trace.NewEventConfig(options...)
+	s.events[name] = config.Attributes()
+}
+
+func spanContext(traceID, spanID string) trace.SpanContext {
+	tid, _ := trace.TraceIDFromHex(traceID)
+	sid, _ := trace.SpanIDFromHex(spanID)
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+}
+
+func traceContext(t *testing.T, data ...interface{}) (map[string]interface{}, map[string]string, string) {
+	s, _ := sentry.FromGlogEvent(withCallers(glog.Event{Severity: "ERROR", Message: []byte("message"), Data: data}), true)
+	b, err := json.Marshal(s.Contexts["trace"])
+	assert.NoError(t, err)
+	var tc map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &tc))
+	return tc, s.Tags, string(s.EventID)
+}
+
+func TestTraceFromContext(t *testing.T) {
+	span := &recordingSpan{
+		Span:   trace.SpanFromContext(context.Background()),
+		sc:     spanContext("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"),
+		parent: spanContext("4bf92f3577b34da6a3ce929d0e0e4736", "b7ad6b7169203331"),
+		events: map[string][]attribute.KeyValue{},
+	}
+	ctx := trace.ContextWithSpan(context.Background(), span)
+
+	tc, tags, id := traceContext(t, ctx)
+	assert.Equal(t, map[string]interface{}{
+		"trace_id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":        "00f067aa0ba902b7",
+		"parent_span_id": "b7ad6b7169203331",
+	}, tc)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tags[sentry.TraceIDTag])
+	assert.Equal(t, "00f067aa0ba902b7", tags[sentry.SpanIDTag])
+
+	// The span is only annotated once the event is captured
+	assert.Empty(t, id)
+	assert.Empty(t, span.events)
+
+	// Contexts without a span are ignored
+	tc, _, id = traceContext(t, context.Background())
+	assert.Nil(t, tc)
+	assert.Empty(t, id)
+}
+
+func TestTraceParent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	want := map[string]interface{}{
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}
+
+	tc, tags, _ := traceContext(t, sentry.TraceParent(header))
+	assert.Equal(t, want, tc)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tags[sentry.TraceIDTag])
+
+	// Plain strings are not taken as trace context
+	tc, _, _ = traceContext(t, header)
+	assert.Nil(t, tc)
+
+	tc, _, _ = traceContext(t, sentry.TraceParent("00-00000000000000000000000000000000-00f067aa0ba902b7-01"))
+	assert.Nil(t, tc)
+}
+
+func TestTraceRecordsCapturedEvents(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	capture := func(opts sentrygo.ClientOptions) *recordingSpan {
+		span := &recordingSpan{
+			Span:   trace.SpanFromContext(context.Background()),
+			sc:     spanContext("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"),
+			events: map[string][]attribute.KeyValue{},
+		}
+		b, err := sentry.NewBackend(sentry.Config{DSNs: []string{server.DSN("1")}}, opts)
+		require.NoError(t, err)
+		comm := make(chan glog.Event, 1)
+		comm <- withCallers(glog.Event{
+			Severity: "ERROR",
+			Message:  []byte("message"),
+			Data:     []interface{}{trace.ContextWithSpan(context.Background(), span)},
+		})
+		close(comm)
+		b.Run(comm)
+		return span
+	}
+
+	span := capture(sentrygo.ClientOptions{})
+	events := server.RequireEvents(t, 1)
+	assert.Equal(t, []attribute.KeyValue{attribute.String(sentry.SpanEventIDAttr, string(events[0].EventID))},
+		span.events[sentry.SpanEventName])
+
+	// Events which are dropped are not recorded
+	server.Reset()
+	span = capture(sentrygo.ClientOptions{BeforeSend: func(*sentrygo.Event, *sentrygo.EventHint) *sentrygo.Event {
+		return nil
+	}})
+	assert.Empty(t, server.Events())
+	assert.Empty(t, span.events)
+}
+
+func TestTraceEndedSpan(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	span := &recordingSpan{
+		Span:   trace.SpanFromContext(context.Background()),
+		sc:     spanContext("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"),
+		events: map[string][]attribute.KeyValue{},
+	}
+	b, err := sentry.NewBackend(sentry.Config{DSNs: []string{server.DSN("1")}}, sentrygo.ClientOptions{})
+	require.NoError(t, err)
+
+	// The span ends right after logging, before the backend captures the
+	// event, so it is only linked to the trace by its context and tags.
+	comm := make(chan glog.Event, 1)
+	comm <- withCallers(glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("message"),
+		Data:     []interface{}{trace.ContextWithSpan(context.Background(), span)},
+	})
+	span.End()
+	close(comm)
+	b.Run(comm)
+
+	events := server.RequireEvents(t, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", events[0].Tags[sentry.TraceIDTag])
+	assert.Equal(t, "00f067aa0ba902b7", events[0].Tags[sentry.SpanIDTag])
+	assert.Empty(t, span.events)
+}
@@ -2,7 +2,10 @@ package sentry_test
 
 import (
 	"flag"
+	"os"
 	"runtime"
+	"sync"
+	"testing"
 	"time"
 
 	sentrygo "github.com/getsentry/sentry-go"
@@ -15,17 +18,53 @@ const pkgName = "github.com/yext/glog-contrib/sentry_test" // this should stay i
 const fileNameSuffix = "_test.go"                          // this should stay in sync with the name of this file
 
 var sendToDsn = flag.String("sendToDsn", "",
-	"optional sentry DSN. if set, sample exceptions will be sent to Sentry as an integration test")
+	"optional sentry DSN. if set, sample exceptions will be sent to Sentry as an integration test. "+
+		"a file:// DSN (e.g. file:///tmp/sentry.jsonl) writes the events to a local file instead")
 
 var logEvents = flag.Bool("logEvents", false,
 	"if set, full log messages will be pretty-printed to the screen")
 
+// glogBackends fans out the events of the single glog backend registered by
+// TestMain. glog doesn't synchronize registering backends with sending
+// events to them, so tests subscribe here instead.
+var glogBackends struct {
+	sync.Mutex
+	chans []chan glog.Event
+}
+
+func TestMain(m *testing.M) {
+	events := glog.RegisterBackend()
+	go func() {
+		for e := range events {
+			glogBackends.Lock()
+			for _, c := range glogBackends.chans {
+				select {
+				case c <- e:
+				default:
+				}
+			}
+			glogBackends.Unlock()
+		}
+	}()
+	os.Exit(m.Run())
+}
+
+// registerBackend returns a channel which receives the glog events logged
+// from now on, like glog.RegisterBackend.
+func registerBackend() <-chan glog.Event {
+	c := make(chan glog.Event, 100)
+	glogBackends.Lock()
+	glogBackends.chans = append(glogBackends.chans, c)
+	glogBackends.Unlock()
+	return c
+}
+
 func setup(ready chan interface{}, done chan *sentrygo.Event, count int, dedup bool) {
 	sentry.CaptureErrors(
 		"example",
 		[]string{*sendToDsn},
 		sentrygo.ClientOptions{Debug: true},
-		wrapper(ready, done, count, dedup, glog.RegisterBackend()))
+		wrapper(ready, done, count, dedup, registerBackend()))
 }
 
 func wrapper(ready chan interface{}, done chan *sentrygo.Event, count int, dedup bool, ch <-chan glog.Event) <-chan glog.Event {
@@ -0,0 +1,382 @@
+// Package sentrytest provides an in-process fake Sentry server for
+// integration tests. It accepts events sent to both the legacy store
+// endpoint, used by the raven package, and the envelope endpoint, used by
+// sentry-go, and records them so that tests can wait for and inspect them.
+package sentrytest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// DefaultTimeout is the time RequireEvents waits for events to arrive.
+const DefaultTimeout = 5 * time.Second
+
+const (
+	// PublicKey and SecretKey are the keys included in DSNs for the server.
+	PublicKey = "public"
+	SecretKey = "secret"
+)
+
+// The endpoints which events are received on.
+const (
+	EndpointStore    = "store"
+	EndpointEnvelope = "envelope"
+)
+
+var endpointRe = regexp.MustCompile(`^/api/([^/]+)/(store|envelope)/?$`)
+
+// Event is an event received by the server.
+type Event struct {
+	// Project is the project ID from the request path.
+	Project string
+	// Endpoint is the endpoint the event was sent to, EndpointStore or EndpointEnvelope.
+	Endpoint string
+	// Payload is the JSON encoded event, as it was sent.
+	Payload json.RawMessage
+	// Event is the decoded event.
+	*sentry.Event
+}
+
+// Server is a fake Sentry server. Events sent to it are recorded and
+// acknowledged, while requests which are malformed or have invalid
+// authentication are rejected and recorded as errors.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	events []Event
+	errors []error
+	notify chan struct{}
+}
+
+// NewServer starts a new server. It should be closed when the test completes.
+func NewServer() *Server {
+	s := &Server{notify: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// DSN returns a DSN which sends events for the project to the server.
+func (s *Server) DSN(project string) string {
+	return strings.Replace(s.URL, "://", "://"+PublicKey+":"+SecretKey+"@", 1) + "/" + project
+}
+
+// Events returns the events received so far, in the order they arrived.
+func (s *Server) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// ProjectEvents returns the events received so far for the project.
+func (s *Server) ProjectEvents(project string) []Event {
+	var events []Event
+	for _, e := range s.Events() {
+		if e.Project == project {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Errors returns the errors for any requests which were rejected.
+func (s *Server) Errors() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error(nil), s.errors...)
+}
+
+// Reset discards all received events and errors.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+	s.errors = nil
+}
+
+// WaitForEvents waits until at least n events have been received, and
+// returns them. It returns an error if the timeout elapses first.
+func (s *Server) WaitForEvents(n int, timeout time.Duration) ([]Event, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		events, notify := append([]Event(nil), s.events...), s.notify
+		s.mu.Unlock()
+		if len(events) >= n {
+			return events, nil
+		}
+
+		select {
+		case <-notify:
+		case <-deadline:
+			return events, fmt.Errorf("received %d events, expected %d", len(events), n)
+		}
+	}
+}
+
+// RequireEvents waits for exactly n events to be received within
+// DefaultTimeout, failing the test otherwise, and returns them.
+// It also fails the test if any requests were rejected.
+func (s *Server) RequireEvents(t testing.TB, n int) []Event {
+	t.Helper()
+	events, err := s.WaitForEvents(n, DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != n {
+		t.Fatalf("received %d events, expected %d", len(events), n)
+	}
+	for _, err := range s.Errors() {
+		t.Errorf("rejected request: %v", err)
+	}
+	return events
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	events, err := s.decodeRequest(r)
+	if err != nil {
+		s.mu.Lock()
+		s.errors = append(s.errors, err)
+		s.mu.Unlock()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.events = append(s.events, events...)
+	close(s.notify)
+	s.notify = make(chan struct{})
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	id := ""
+	if len(events) > 0 {
+		id = string(events[0].EventID)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
+
+func (s *Server) decodeRequest(r *http.Request) ([]Event, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("unexpected method %s %s", r.Method, r.URL.Path)
+	}
+	m := endpointRe.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		return nil, fmt.Errorf("unexpected path %s", r.URL.Path)
+	}
+	project, endpoint := m[1], m[2]
+
+	if err := checkAuth(r); err != nil {
+		return nil, err
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads [][]byte
+	if endpoint == EndpointEnvelope {
+		payloads, err = decodeEnvelope(body)
+	} else {
+		var payload []byte
+		payload, err = decodeStore(body)
+		payloads = [][]byte{payload}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, p := range payloads {
+		e, err := decodeEvent(p)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, Event{
+			Project:  project,
+			Endpoint: endpoint,
+			Payload:  p,
+			Event:    e,
+		})
+	}
+	return events, nil
+}
+
+// checkAuth validates the X-Sentry-Auth header, or the equivalent query
+// parameters, against the server's public key.
+func checkAuth(r *http.Request) error {
+	params := map[string]string{}
+	if auth := r.Header.Get("X-Sentry-Auth"); auth != "" {
+		if !strings.HasPrefix(auth, "Sentry ") {
+			return fmt.Errorf("malformed X-Sentry-Auth header: %q", auth)
+		}
+		for _, kv := range strings.Split(strings.TrimPrefix(auth, "Sentry "), ",") {
+			parts := strings.SplitN(strings.TrimSpace(kv), "=", 2)
+			if len(parts) == 2 {
+				params[parts[0]] = parts[1]
+			}
+		}
+	} else {
+		for k := range r.URL.Query() {
+			params[k] = r.URL.Query().Get(k)
+		}
+	}
+
+	if params["sentry_key"] != PublicKey {
+		return fmt.Errorf("invalid sentry_key %q", params["sentry_key"])
+	}
+	if secret, ok := params["sentry_secret"]; ok && secret != SecretKey {
+		return fmt.Errorf("invalid sentry_secret %q", secret)
+	}
+	if params["sentry_version"] == "" {
+		return fmt.Errorf("missing sentry_version")
+	}
+	return nil
+}
+
+// readBody reads the request body, removing any content encoding.
+func readBody(r *http.Request) ([]byte, error) {
+	var reader io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		reader = gz
+	case "deflate":
+		z, err := zlib.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		reader = z
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", r.Header.Get("Content-Encoding"))
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// decodeStore decodes a store request, which is either plain JSON or, as
+// sent by the raven package, base64 encoded zlib compressed JSON.
+func decodeStore(body []byte) ([]byte, error) {
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("{")) {
+		return body, nil
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("decoding store request: %v", err)
+	}
+	z, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing store request: %v", err)
+	}
+	return ioutil.ReadAll(z)
+}
+
+// decodeEnvelope returns the payloads of the event and transaction items
+// in an envelope. Other items, such as attachments, are skipped.
+func decodeEnvelope(body []byte) ([][]byte, error) {
+	r := bufio.NewReader(bytes.NewReader(body))
+
+	header, err := r.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !json.Valid(bytes.TrimSpace(header)) {
+		return nil, fmt.Errorf("malformed envelope header: %q", header)
+	}
+
+	var payloads [][]byte
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return payloads, nil
+			}
+			continue
+		}
+
+		var item struct {
+			Type   string `json:"type"`
+			Length *int   `json:"length"`
+		}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("malformed envelope item header: %q", line)
+		}
+
+		var payload []byte
+		if item.Length != nil {
+			payload = make([]byte, *item.Length)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return nil, fmt.Errorf("reading envelope item: %v", err)
+			}
+		} else {
+			payload, _ = r.ReadBytes('\n')
+		}
+
+		if item.Type == "event" || item.Type == "transaction" {
+			payloads = append(payloads, bytes.TrimSpace(payload))
+		}
+	}
+}
+
+// decodeEvent decodes the JSON payload of an event. Timestamps without a
+// time zone, as sent by the raven package, are treated as UTC.
+func decodeEvent(payload []byte) (*sentry.Event, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, fmt.Errorf("malformed event: %v", err)
+	}
+	for _, k := range []string{"timestamp", "start_timestamp"} {
+		switch ts := fields[k].(type) {
+		case string:
+			if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+				fields[k] = ts + "Z"
+			}
+		case float64:
+			sec, frac := int64(ts), ts-float64(int64(ts))
+			fields[k] = time.Unix(sec, int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var e sentry.Event
+	if err := json.Unmarshal(normalized, &e); err == nil {
+		return &e, nil
+	}
+
+	// The request interface sent by the raven package allows arbitrary data,
+	// which does not fit sentry.Request, so it is only available in the Payload.
+	delete(fields, "request")
+	if normalized, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+	e = sentry.Event{}
+	if err := json.Unmarshal(normalized, &e); err != nil {
+		return nil, fmt.Errorf("malformed event: %v", err)
+	}
+	return &e, nil
+}
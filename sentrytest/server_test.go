@@ -0,0 +1,92 @@
+package sentrytest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"testing"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yext/glog-contrib/raven"
+	"github.com/yext/glog-contrib/sentrytest"
+)
+
+func TestSentryClient(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	client, err := sentrygo.NewClient(sentrygo.ClientOptions{Dsn: server.DSN("1")})
+	assert.NoError(t, err)
+	client.CaptureMessage("envelope message", nil, nil)
+	assert.True(t, client.Flush(5*time.Second))
+
+	events := server.RequireEvents(t, 1)
+	assert.Equal(t, "1", events[0].Project)
+	assert.Equal(t, sentrytest.EndpointEnvelope, events[0].Endpoint)
+	assert.Equal(t, "envelope message", events[0].Message)
+}
+
+func TestRavenClient(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	client, err := raven.NewClient(server.DSN("2"))
+	assert.NoError(t, err)
+	_, err = client.CaptureMessage("store message")
+	assert.NoError(t, err)
+
+	events := server.RequireEvents(t, 1)
+	assert.Equal(t, "2", events[0].Project)
+	assert.Equal(t, sentrytest.EndpointStore, events[0].Endpoint)
+	assert.Equal(t, "store message", events[0].Message)
+	assert.False(t, events[0].Timestamp.IsZero())
+}
+
+func TestGzipEnvelope(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte(`{"event_id":"abc"}` + "\n" +
+		`{"type":"attachment","length":5}` + "\n" + "hello\n" +
+		`{"type":"event"}` + "\n" + `{"event_id":"abc","message":"compressed"}` + "\n"))
+	gz.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/3/envelope/", &body)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_key=public")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	events := server.RequireEvents(t, 1)
+	assert.Equal(t, "compressed", events[0].Message)
+}
+
+func TestInvalidAuth(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/1/store/", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_key=wrong")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Len(t, server.Errors(), 1)
+	assert.Empty(t, server.Events())
+
+	server.Reset()
+	assert.Empty(t, server.Errors())
+}
+
+func TestWaitForEventsTimeout(t *testing.T) {
+	server := sentrytest.NewServer()
+	defer server.Close()
+
+	events, err := server.WaitForEvents(1, 10*time.Millisecond)
+	assert.Error(t, err)
+	assert.Empty(t, events)
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -33,3 +34,9 @@ func TestGuessAbsPath(t *testing.T) {
 	assert.Equal(t, "/path/to/foo/bar.go", stacktrace.GuessAbsPath("/path/to/foo/bar.go"))
 	assert.Equal(t, path.Join(gopath, "foo/bar.go"), stacktrace.GuessAbsPath(path.Join(gopath, "foo/bar.go")))
 }
+
+func TestNewFrameFilename(t *testing.T) {
+	frame := stacktrace.NewFrame(runtime.Frame{Function: "main.main", File: "/go/src/yext/examples/example.go", Line: 10})
+	assert.Equal(t, "yext/examples/example.go", frame.Filename)
+	assert.Equal(t, "/go/src/yext/examples/example.go", frame.AbsPath)
+}
@@ -0,0 +1,161 @@
+package stacktrace
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+var (
+	goroutineHeaderRe = regexp.MustCompile(`^goroutine (\d+) \[(.*)\]:$`)
+	goroutineFileRe   = regexp.MustCompile(`^\t(.*):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	goroutineWaitRe   = regexp.MustCompile(`^(\d+) minutes$`)
+)
+
+// Goroutine describes a single goroutine from the output of runtime.Stack.
+type Goroutine struct {
+	ID int
+	// State is the scheduler state of the goroutine, e.g. "running" or "chan receive".
+	State string
+	// Wait is the approximate time the goroutine has been blocked, if reported.
+	// The runtime only reports this with minute granularity.
+	Wait time.Duration
+	// LockedToThread is set if the goroutine is wired to its OS thread.
+	LockedToThread bool
+	// Frames are ordered innermost (most recent call) first, matching the
+	// order of the dump. The final frame is the "created by" call site, if any.
+	Frames []runtime.Frame
+}
+
+// AllGoroutines returns the parsed stacks of all goroutines. The goroutine
+// calling AllGoroutines is always the first entry.
+func AllGoroutines() []Goroutine {
+	return ParseGoroutines(allStacks())
+}
+
+// allStacks is a wrapper for runtime.Stack which grows the buffer until
+// the stacks of all goroutines fit.
+func allStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// ParseGoroutines parses the output of runtime.Stack (or of a Go panic)
+// into individual goroutines. Unrecognized lines are skipped.
+func ParseGoroutines(dump []byte) []Goroutine {
+	var (
+		goroutines []Goroutine
+		current    *Goroutine
+		function   string
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := goroutineHeaderRe.FindStringSubmatch(line); m != nil {
+			goroutines = append(goroutines, newGoroutine(m[1], m[2]))
+			current = &goroutines[len(goroutines)-1]
+			function = ""
+			continue
+		}
+		if current == nil || line == "" {
+			continue
+		}
+		if m := goroutineFileRe.FindStringSubmatch(line); m != nil {
+			if function == "" {
+				continue
+			}
+			lineno, _ := strconv.Atoi(m[2])
+			current.Frames = append(current.Frames, runtime.Frame{
+				Function: function,
+				File:     m[1],
+				Line:     lineno,
+			})
+			function = ""
+			continue
+		}
+		function = functionName(line)
+	}
+
+	return goroutines
+}
+
+func newGoroutine(id, status string) Goroutine {
+	g := Goroutine{}
+	g.ID, _ = strconv.Atoi(id)
+
+	// The status is a comma-separated list, such as
+	// "chan receive, 3 minutes, locked to thread".
+	parts := strings.Split(status, ", ")
+	g.State = parts[0]
+	for _, p := range parts[1:] {
+		if m := goroutineWaitRe.FindStringSubmatch(p); m != nil {
+			minutes, _ := strconv.Atoi(m[1])
+			g.Wait = time.Duration(minutes) * time.Minute
+		} else if p == "locked to thread" {
+			g.LockedToThread = true
+		}
+	}
+	return g
+}
+
+// functionName extracts the function name from a line of the dump, which
+// is either a call such as "pkg.(*T).Method(0x1, 0x2)" or a goroutine
+// origin such as "created by pkg.Func in goroutine 1".
+func functionName(line string) string {
+	if strings.HasPrefix(line, "created by ") {
+		line = strings.TrimPrefix(line, "created by ")
+		if i := strings.Index(line, " in goroutine "); i != -1 {
+			line = line[:i]
+		}
+		return line
+	}
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			return line[:i]
+		}
+	}
+	if strings.HasPrefix(line, "...") {
+		// e.g. "...additional frames elided..."
+		return ""
+	}
+	return line
+}
+
+// Stacktrace converts the frames of the goroutine into a Sentry stacktrace,
+// ordered outermost first, with Go internal frames removed.
+func (g Goroutine) Stacktrace() *sentry.Stacktrace {
+	var frames []sentry.Frame
+	for i := len(g.Frames) - 1; i >= 0; i-- {
+		frames = append(frames, NewFrame(g.Frames[i]))
+	}
+	frames = filterFrames(frames)
+	if len(frames) == 0 {
+		return nil
+	}
+	return &sentry.Stacktrace{Frames: frames}
+}
+
+// HasFrame returns whether the goroutine is currently executing, or waiting
+// on a call from, the given function at the given line.
+func (g Goroutine) HasFrame(function string, line int) bool {
+	for _, f := range g.Frames {
+		if f.Function == function && f.Line == line {
+			return true
+		}
+	}
+	return false
+}
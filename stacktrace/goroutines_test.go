@@ -0,0 +1,83 @@
+package stacktrace_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yext/glog-contrib/stacktrace"
+)
+
+const goroutineDump = `goroutine 1 [running]:
+main.main()
+	/go/src/example/main.go:12 +0xbb
+
+goroutine 6 [chan receive, 3 minutes, locked to thread]:
+example/worker.(*Pool).run(0xc000010000, {0x0, 0x0})
+	/go/src/example/worker/pool.go:48 +0x19
+created by example/worker.NewPool in goroutine 1
+	/go/src/example/worker/pool.go:20 +0x76
+
+goroutine 7 [sleep]:
+time.Sleep(0x34630b8a000)
+	/usr/local/go/src/runtime/time.go:368 +0x165
+main.main.func2()
+	/go/src/example/main.go:9 +0x1d
+...additional frames elided...
+`
+
+func TestParseGoroutines(t *testing.T) {
+	goroutines := stacktrace.ParseGoroutines([]byte(goroutineDump))
+	assert.Len(t, goroutines, 3)
+
+	g := goroutines[0]
+	assert.Equal(t, 1, g.ID)
+	assert.Equal(t, "running", g.State)
+	assert.Equal(t, time.Duration(0), g.Wait)
+	assert.Len(t, g.Frames, 1)
+	assert.Equal(t, "main.main", g.Frames[0].Function)
+	assert.Equal(t, "/go/src/example/main.go", g.Frames[0].File)
+	assert.Equal(t, 12, g.Frames[0].Line)
+
+	g = goroutines[1]
+	assert.Equal(t, 6, g.ID)
+	assert.Equal(t, "chan receive", g.State)
+	assert.Equal(t, 3*time.Minute, g.Wait)
+	assert.True(t, g.LockedToThread)
+	assert.Len(t, g.Frames, 2)
+	assert.Equal(t, "example/worker.(*Pool).run", g.Frames[0].Function)
+	assert.Equal(t, "example/worker.NewPool", g.Frames[1].Function)
+	assert.True(t, g.HasFrame("example/worker.(*Pool).run", 48))
+	assert.False(t, g.HasFrame("example/worker.(*Pool).run", 49))
+
+	g = goroutines[2]
+	assert.Equal(t, "sleep", g.State)
+	assert.Len(t, g.Frames, 2)
+}
+
+func TestGoroutineStacktrace(t *testing.T) {
+	goroutines := stacktrace.ParseGoroutines([]byte(goroutineDump))
+
+	// Frames are outermost first, and Go internal frames are removed
+	st := goroutines[1].Stacktrace()
+	assert.Len(t, st.Frames, 2)
+	assert.Equal(t, "NewPool", st.Frames[0].Function)
+	assert.Equal(t, "(*Pool).run", st.Frames[1].Function)
+	assert.Equal(t, 48, st.Frames[1].Lineno)
+
+	st = goroutines[2].Stacktrace()
+	assert.Len(t, st.Frames, 2)
+	assert.Equal(t, "main.func2", st.Frames[0].Function)
+	assert.Equal(t, "Sleep", st.Frames[1].Function)
+}
+
+func TestAllGoroutines(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	go func() { <-block }()
+
+	goroutines := stacktrace.AllGoroutines()
+	assert.True(t, len(goroutines) >= 2)
+	assert.Equal(t, "running", goroutines[0].State)
+}
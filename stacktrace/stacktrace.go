@@ -76,6 +76,12 @@ func fixUpFrame(frame sentry.Frame) sentry.Frame {
 		frame.AbsPath = GuessAbsPath(frame.Filename)
 	}
 
+	// sentry-go only sets the filename for relative paths, so derive it
+	// from the absolute path otherwise.
+	if frame.Filename == "" {
+		frame.Filename = frame.AbsPath
+	}
+
 	// Clean up the returned filename to remove the gopath
 	frame.Filename = GopathRelativeFile(frame.Filename)
 
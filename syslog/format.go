@@ -0,0 +1,208 @@
+package syslog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/internal/glogheader"
+)
+
+// Format is the format of syslog messages.
+type Format int
+
+const (
+	// RFC5424 is the current syslog format, which supports structured data.
+	RFC5424 Format = iota
+	// RFC3164 is the legacy BSD syslog format. Data maps passed to glog are
+	// not sent, as it has no structured data.
+	RFC3164
+)
+
+// Facility is the syslog facility, identifying the type of program sending
+// a message.
+type Facility int
+
+// Facilities defined by RFC 5424.
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	LPR
+	News
+	UUCP
+	Cron
+	AuthPriv
+	FTP
+)
+
+// Facilities reserved for local use.
+const (
+	Local0 Facility = iota + 16
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// Severity is the syslog severity of a message.
+type Severity int
+
+// Severities, from the most to the least severe.
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Informational
+	Debug
+)
+
+var severities = map[string]Severity{
+	"INFO":    Informational,
+	"WARNING": Warning,
+	"ERROR":   Error,
+	"FATAL":   Critical,
+}
+
+// The maximum lengths of the header fields of RFC 5424 messages, and the
+// TAG of RFC 3164 messages.
+const (
+	maxHostname  = 255
+	maxAppName   = 48
+	maxProcID    = 128
+	maxParamName = 32
+	maxTag       = 32
+)
+
+// formatEvent converts a glog event into a syslog message. The timestamp
+// of the message is taken from the glog header, which is removed, if
+// present.
+func formatEvent(e glog.Event, o Options, pid int, now time.Time) []byte {
+	severity, ok := o.Severities[e.Severity]
+	if !ok {
+		if severity, ok = severities[e.Severity]; !ok {
+			severity = Informational
+		}
+	}
+	pri := int(o.Facility)*8 + int(severity)
+
+	timestamp := now
+	header, msg, ok := glogheader.Parse(string(e.Message), now)
+	if ok {
+		timestamp = header.Time
+	}
+	msg = strings.TrimRight(msg, "\r\n")
+
+	if o.Format == RFC3164 {
+		tag := headerField(o.AppName, maxTag)
+		return []byte(fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+			pri, timestamp.Format(time.Stamp), headerField(o.Hostname, maxHostname), tag, pid, msg))
+	}
+
+	data := map[string]interface{}{}
+	for _, d := range e.Data {
+		if m, ok := d.(map[string]interface{}); ok {
+			for k, v := range m {
+				data[k] = v
+			}
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s - ",
+		pri,
+		timestamp.Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(o.Hostname, maxHostname),
+		headerField(o.AppName, maxAppName),
+		headerField(strconv.Itoa(pid), maxProcID))
+	writeStructuredData(&b, o.SDID, data)
+	if msg != "" {
+		b.WriteString(" ")
+		b.WriteString(msg)
+	}
+	return []byte(b.String())
+}
+
+// writeStructuredData writes the data as a single element with sorted
+// parameters, or the nil value if there is none.
+func writeStructuredData(b *strings.Builder, id string, data map[string]interface{}) {
+	if len(data) == 0 {
+		b.WriteString("-")
+		return
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.WriteString("[")
+	b.WriteString(id)
+	for _, k := range keys {
+		fmt.Fprintf(b, ` %s="%s"`, paramName(k), paramValue(data[k]))
+	}
+	b.WriteString("]")
+}
+
+// headerField replaces the characters which are not printable ASCII in a
+// header field, and truncates it. Empty fields are the nil value "-".
+func headerField(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// paramName replaces the characters not allowed in the name of a
+// structured data parameter, and truncates it.
+func paramName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "_"
+	}
+	if len(s) > maxParamName {
+		s = s[:maxParamName]
+	}
+	return s
+}
+
+var paramValueReplacer = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// paramValue formats a value of a structured data parameter, escaping the
+// characters which must be escaped.
+func paramValue(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case nil:
+	case error:
+		s = t.Error()
+	default:
+		s = fmt.Sprint(t)
+	}
+	return paramValueReplacer.Replace(s)
+}
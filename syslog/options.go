@@ -0,0 +1,88 @@
+package syslog
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Options configures how glog events are sent by Capture.
+type Options struct {
+	// Format of the messages. Defaults to RFC5424.
+	Format Format
+	// Facility of the messages. Defaults to User.
+	Facility Facility
+	// AppName identifies the application, and is sent as the APP-NAME of
+	// RFC 5424 messages and the TAG of RFC 3164 messages. Defaults to the
+	// name of the executable.
+	AppName string
+	// Hostname is sent as the HOSTNAME of messages. Defaults to the
+	// hostname reported by the kernel.
+	Hostname string
+	// Severities overrides the syslog severity glog severities are sent
+	// at. By default INFO is sent as Informational, WARNING as Warning,
+	// ERROR as Error and FATAL as Critical.
+	Severities map[string]Severity
+	// SDID is the ID of the structured data element containing the data
+	// maps passed to glog, in RFC 5424 messages. Defaults to
+	// "glog@32473", using the enterprise number reserved for documentation.
+	SDID string
+}
+
+// Option overrides one of the Options.
+type Option func(*Options)
+
+// WithFormat sets Options.Format.
+func WithFormat(format Format) Option {
+	return func(o *Options) { o.Format = format }
+}
+
+// WithFacility sets Options.Facility.
+func WithFacility(facility Facility) Option {
+	return func(o *Options) { o.Facility = facility }
+}
+
+// WithAppName sets Options.AppName.
+func WithAppName(name string) Option {
+	return func(o *Options) { o.AppName = name }
+}
+
+// WithHostname sets Options.Hostname.
+func WithHostname(name string) Option {
+	return func(o *Options) { o.Hostname = name }
+}
+
+// WithSeverity sets the syslog severity events at a glog severity, such as
+// "INFO", are sent at.
+func WithSeverity(glogSeverity string, severity Severity) Option {
+	return func(o *Options) {
+		if o.Severities == nil {
+			o.Severities = map[string]Severity{}
+		}
+		o.Severities[glogSeverity] = severity
+	}
+}
+
+// WithSDID sets Options.SDID.
+func WithSDID(id string) Option {
+	return func(o *Options) { o.SDID = id }
+}
+
+func buildOptions(options []Option) (Options, error) {
+	o := Options{
+		Format:   RFC5424,
+		Facility: User,
+		AppName:  filepath.Base(os.Args[0]),
+		SDID:     "glog@32473",
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.Hostname == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return o, err
+		}
+		o.Hostname = host
+	}
+	return o, nil
+}
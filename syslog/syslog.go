@@ -0,0 +1,37 @@
+// Package syslog sends glog events to a syslog server, such as rsyslog, in
+// the RFC 5424 or RFC 3164 format.
+package syslog
+
+import (
+	"os"
+	"time"
+
+	"github.com/yext/glog"
+)
+
+// Capture sends events from the channel to the syslog server at the uri
+// (see Dial), until the channel is closed. Maps passed as glog data are sent
+// as structured data in RFC 5424 messages. For example:
+//
+//	go syslog.Capture("unix:///dev/log", glog.RegisterBackend(),
+//		syslog.WithFacility(syslog.Local0))
+func Capture(serverUri string, eventCh <-chan glog.Event, options ...Option) error {
+	o, err := buildOptions(options)
+	if err != nil {
+		return err
+	}
+	w, err := Dial(serverUri)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// Errors sending individual messages are ignored, as they can't be
+	// logged without producing more events, and the messages remain
+	// buffered to be sent once the writer reconnects.
+	pid := os.Getpid()
+	for e := range eventCh {
+		_ = w.WriteMessage(formatEvent(e, o, pid, time.Now()))
+	}
+	return nil
+}
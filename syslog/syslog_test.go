@@ -0,0 +1,93 @@
+package syslog_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog"
+	"github.com/yext/glog-contrib/syslog"
+)
+
+// listenPacket returns a datagram socket, and the URI messages are sent to
+// it with.
+func listenPacket(t *testing.T, network, addr string) (net.PacketConn, string) {
+	conn, err := net.ListenPacket(network, addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	if network == "unixgram" {
+		return conn, "unix://" + addr
+	}
+	return conn, "udp://" + conn.LocalAddr().String()
+}
+
+func receive(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 65536)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+// capture sends the event with Capture over UDP, and returns the message
+// received.
+func capture(t *testing.T, e glog.Event, options ...syslog.Option) string {
+	conn, uri := listenPacket(t, "udp", "127.0.0.1:0")
+	ch := make(chan glog.Event, 1)
+	ch <- e
+	close(ch)
+	options = append([]syslog.Option{syslog.WithHostname("web-1"), syslog.WithAppName("api")}, options...)
+	require.NoError(t, syslog.Capture(uri, ch, options...))
+	return receive(t, conn)
+}
+
+func TestCaptureRFC5424(t *testing.T) {
+	year := time.Now().Year()
+	timestamp := time.Date(year, 1, 2, 15, 4, 5, 123456000, time.Local).Format("2006-01-02T15:04:05.000000Z07:00")
+	e := glog.Event{
+		Severity: "ERROR",
+		Message:  []byte("E0102 15:04:05.123456 server.go:42] request failed\n"),
+		Data: []interface{}{
+			map[string]interface{}{"user id": "alice", "path": `/a]"b\c`},
+			map[string]interface{}{"status": 502},
+		},
+	}
+	expected := fmt.Sprintf(`<11>1 %s web-1 api %d - [glog@32473 path="/a\]\"b\\c" status="502" user_id="alice"] request failed`,
+		timestamp, os.Getpid())
+	assert.Equal(t, expected, capture(t, e))
+
+	// Messages without data or a glog header are sent at the current time
+	e = glog.Event{Severity: "INFO", Message: []byte("started")}
+	m := capture(t, e, syslog.WithFacility(syslog.Local0), syslog.WithSeverity("INFO", syslog.Notice), syslog.WithSDID("app@12345"))
+	assert.Regexp(t, fmt.Sprintf(`^<133>1 \S+ web-1 api %d - - started$`, os.Getpid()), m)
+}
+
+func TestCaptureRFC3164(t *testing.T) {
+	e := glog.Event{
+		Severity: "FATAL",
+		Message:  []byte("F0102 15:04:05.123456 main.go:10] crashed"),
+		Data:     []interface{}{map[string]interface{}{"ignored": true}},
+	}
+	expected := fmt.Sprintf("<10>Jan  2 15:04:05 web-1 api[%d]: crashed", os.Getpid())
+	assert.Equal(t, expected, capture(t, e, syslog.WithFormat(syslog.RFC3164)))
+}
+
+func TestUnixWriter(t *testing.T) {
+	conn, uri := listenPacket(t, "unixgram", filepath.Join(t.TempDir(), "log"))
+	w, err := syslog.Dial(uri)
+	require.NoError(t, err)
+	defer w.Close()
+
+	require.NoError(t, w.WriteMessage([]byte("<14>1 - - - - - - hello")))
+	assert.Equal(t, "<14>1 - - - - - - hello", receive(t, conn))
+
+	_, err = syslog.Dial("unix://" + filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+	_, err = syslog.Dial("http://localhost")
+	assert.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yext/glog-contrib/internal/reconnect"
+	"github.com/yext/glog-contrib/internal/tlsconfig"
+)
+
+// Default ports of syslog servers.
+const (
+	DefaultPort    = "514"
+	DefaultTLSPort = "6514"
+)
+
+// ConnectionStats are statistics of a Writer.
+type ConnectionStats struct {
+	// Connected is whether the writer is currently connected.
+	Connected bool
+	// Buffered is the number of messages waiting to be sent.
+	Buffered int
+	// Dropped is the number of messages dropped because the buffer was full.
+	Dropped uint64
+	// Failed is the number of messages dropped because writing them failed
+	// for a reason other than the connection, such as datagrams which are
+	// too large.
+	Failed uint64
+}
+
+// Writer sends syslog messages to a server. If the connection fails, or is
+// closed by the server, messages are buffered while it reconnects in the
+// background, with exponential backoff and jitter.
+type Writer struct {
+	w *reconnect.Writer
+	// framed is whether messages are framed with octet counting, as over
+	// TCP and TLS (RFC 6587).
+	framed bool
+}
+
+// Dial connects to a syslog server at a URI with one of the schemes:
+//
+//	unix  a local datagram socket, such as unix:///dev/log
+//	udp   messages are sent in datagrams, to port 514 by default
+//	tcp   messages are framed with octet counting, to port 514 by default
+//	tls   as tcp, but encrypted, to port 6514 by default
+//
+// The query parameters of the URI configure the connection:
+//
+//	timeout               of connecting and writing, such as "5s" (default 10s)
+//	buffer_size           number of messages buffered while reconnecting (default 1000)
+//	max_backoff           longest time waited between attempts to reconnect (default 30s)
+//	ca                    file of PEM encoded certificates to verify the server with
+//	cert, key             files of the PEM encoded client certificate and key
+//	server_name           name used to verify the server, and sent with SNI
+//	insecure_skip_verify  disables verification of the server, if "true"
+//
+// An error is returned if the first connection fails.
+func Dial(uri string) (*Writer, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	config := reconnect.Config{WriteTimeout: 10 * time.Second}
+	if v := query.Get("timeout"); v != "" {
+		if config.WriteTimeout, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("timeout: %v", err)
+		}
+	}
+	if v := query.Get("buffer_size"); v != "" {
+		if config.BufferSize, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("buffer_size: %v", err)
+		}
+	}
+	if v := query.Get("max_backoff"); v != "" {
+		if config.MaxBackoff, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("max_backoff: %v", err)
+		}
+	}
+
+	host := func(port string) string {
+		if u.Port() == "" {
+			return net.JoinHostPort(u.Hostname(), port)
+		}
+		return u.Host
+	}
+	var (
+		dialer = &net.Dialer{Timeout: config.WriteTimeout}
+		dial   func() (net.Conn, error)
+		framed bool
+	)
+	switch u.Scheme {
+	case "unix":
+		dial = func() (net.Conn, error) { return dialer.Dial("unixgram", u.Path) }
+	case "udp":
+		addr := host(DefaultPort)
+		dial = func() (net.Conn, error) { return dialer.Dial("udp", addr) }
+	case "tcp":
+		addr := host(DefaultPort)
+		dial = func() (net.Conn, error) { return dialer.Dial("tcp", addr) }
+		// Syslog servers never send data over streams.
+		framed, config.Watch = true, true
+	case "tls":
+		tlsConfig, err := tlsconfig.FromQuery(query, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		addr := host(DefaultTLSPort)
+		dial = func() (net.Conn, error) { return tls.DialWithDialer(dialer, "tcp", addr, tlsConfig) }
+		framed, config.Watch = true, true
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q: must be unix, udp, tcp or tls", u.Scheme)
+	}
+
+	w, err := reconnect.New(dial, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, framed: framed}, nil
+}
+
+// WriteMessage buffers the message, and sends the buffered messages if
+// connected. It returns an error if they can't be sent, in which case they
+// remain buffered until the writer reconnects. When the buffer is full, the
+// oldest messages are dropped.
+func (w *Writer) WriteMessage(msg []byte) error {
+	if w.framed {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	return w.w.Write(msg)
+}
+
+// Stats returns statistics of the writer.
+func (w *Writer) Stats() ConnectionStats {
+	s := w.w.Stats()
+	return ConnectionStats{
+		Connected: s.Connected,
+		Buffered:  s.Buffered,
+		Dropped:   s.Dropped,
+		Failed:    s.Failed,
+	}
+}
+
+// Close attempts to send any buffered messages, and closes the connection.
+// It returns an error if any messages were not sent.
+func (w *Writer) Close() error {
+	return w.w.Close()
+}
@@ -0,0 +1,55 @@
+package syslog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yext/glog-contrib/internal/testserver"
+	"github.com/yext/glog-contrib/syslog"
+)
+
+func TestTCPWriter(t *testing.T) {
+	server := testserver.NewTCP(t, testserver.OctetCounted)
+	w, err := syslog.Dial("tcp://" + server.Addr + "?buffer_size=2")
+	require.NoError(t, err)
+
+	// Messages containing newlines are framed by their length
+	require.NoError(t, w.WriteMessage([]byte("<14>1 - - - - - - one\ntwo")))
+	assert.Equal(t, "<14>1 - - - - - - one\ntwo", server.Receive(t))
+
+	// The closed connection is detected, and messages are buffered
+	server.Stop()
+	assert.Eventually(t, func() bool { return !w.Stats().Connected }, 5*time.Second, 10*time.Millisecond)
+	for _, m := range []string{"a", "b", "c"} {
+		assert.Error(t, w.WriteMessage([]byte(m)))
+	}
+	assert.Equal(t, syslog.ConnectionStats{Buffered: 2, Dropped: 1}, w.Stats())
+
+	// Closing makes a final attempt to send the buffered messages
+	server.Start(t)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "b", server.Receive(t))
+	assert.Equal(t, "c", server.Receive(t))
+	assert.Error(t, w.WriteMessage([]byte("closed")))
+
+	_, err = syslog.Dial("tcp://" + server.Addr + "?max_backoff=soon")
+	assert.Error(t, err)
+	_, err = syslog.Dial("tls://" + server.Addr + "?ca=/does/not/exist")
+	assert.Error(t, err)
+}
+
+func TestUDPWriterOversizedMessage(t *testing.T) {
+	conn, uri := listenPacket(t, "udp", "127.0.0.1:0")
+	w, err := syslog.Dial(uri)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// A datagram larger than the maximum size can never be sent, so it is
+	// dropped rather than retried, and the next message is sent
+	assert.Error(t, w.WriteMessage(make([]byte, 70000)))
+	require.NoError(t, w.WriteMessage([]byte("<14>1 - - - - - - after")))
+	assert.Equal(t, "<14>1 - - - - - - after", receive(t, conn))
+	assert.Equal(t, syslog.ConnectionStats{Connected: true, Failed: 1}, w.Stats())
+}